@@ -0,0 +1,45 @@
+//go:build intrusive_debug
+// +build intrusive_debug
+
+package intrusive
+
+import "unsafe"
+
+// debugMode indicates whether the package is built with the build tag
+// intrusive_debug, under which nodes keep track of the containers owning
+// them, and misuses of nodes panic instead of corrupting containers.
+const debugMode = true
+
+// nodeOwner records the container owning a node.
+type nodeOwner struct {
+	container unsafe.Pointer
+}
+
+func (no *nodeOwner) attach(container unsafe.Pointer, nodeType string) {
+	if no.container != nil {
+		panic("intrusive: " + nodeType + " already in a container")
+	}
+
+	no.container = container
+}
+
+func (no *nodeOwner) detach() {
+	no.container = nil
+}
+
+func (no *nodeOwner) check(container unsafe.Pointer, nodeType string) {
+	if no.container != container {
+		no.checkAttached(nodeType)
+		panic("intrusive: " + nodeType + " in another container")
+	}
+}
+
+func (no *nodeOwner) checkAttached(nodeType string) {
+	if no.container == nil {
+		panic("intrusive: " + nodeType + " not in any container (removed already?)")
+	}
+}
+
+func (no *nodeOwner) getContainer() unsafe.Pointer {
+	return no.container
+}
@@ -0,0 +1,84 @@
+//go:build intrusive_debug
+// +build intrusive_debug
+
+package intrusive_test
+
+import (
+	"testing"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugList(t *testing.T) {
+	l1 := new(intrusive.List).Init()
+	l2 := new(intrusive.List).Init()
+	var r1, r2 recordOfList
+	l1.AppendNode(&r1.ListNode)
+	assert.PanicsWithValue(t, "intrusive: ListNode already in a container", func() { l2.AppendNode(&r1.ListNode) })
+	assert.PanicsWithValue(t, "intrusive: ListNode in another container", func() { l2.RemoveNode(&r1.ListNode) })
+	assert.PanicsWithValue(t, "intrusive: ListNode in another container", func() { l2.InsertNodeAfter(&r2.ListNode, &r1.ListNode) })
+	assert.PanicsWithValue(t, "intrusive: ListNode not in any container (removed already?)", func() { r1.ListNode.InsertBefore(&r2.ListNode) })
+	l1.InsertNodeAfter(&r2.ListNode, &r1.ListNode)
+	r1.ListNode.Remove()
+	assert.PanicsWithValue(t, "intrusive: ListNode not in any container (removed already?)", func() { r1.ListNode.Remove() })
+	assert.PanicsWithValue(t, "intrusive: ListNode not in any container (removed already?)", func() { l1.RemoveNode(&r1.ListNode) })
+	l2.AppendNodes(l1)
+	assert.PanicsWithValue(t, "intrusive: ListNode in another container", func() { l1.RemoveNode(&r2.ListNode) })
+	l2.RemoveNode(&r2.ListNode)
+	assert.True(t, l2.IsEmpty())
+}
+
+func TestDebugRBTree(t *testing.T) {
+	rbt1 := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	rbt2 := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [10]recordOfRBTree
+	for i := range rs {
+		rs[i].Value = i
+		rbt1.InsertNode(&rs[i].RBTreeNode)
+	}
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode already in a container", func() { rbt2.InsertNode(&rs[0].RBTreeNode) })
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode in another container", func() { rbt2.RemoveNode(&rs[0].RBTreeNode) })
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode in another container", func() { rs[0].RBTreeNode.GetNext(rbt2) })
+	rbt1.RemoveNode(&rs[0].RBTreeNode)
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode not in any container (removed already?)", func() { rbt1.RemoveNode(&rs[0].RBTreeNode) })
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode not in any container (removed already?)", func() { rbt1.UpdateNode(&rs[0].RBTreeNode) })
+	rbt1.SplitAt(5, rbt2)
+	assert.PanicsWithValue(t, "intrusive: RBTreeNode in another container", func() { rbt1.RemoveNode(&rs[5].RBTreeNode) })
+	rbt2.RemoveNode(&rs[5].RBTreeNode)
+	rbt1.Join(rbt2)
+	for i := 1; i < len(rs); i++ {
+		if i != 5 {
+			rbt1.RemoveNode(&rs[i].RBTreeNode)
+		}
+	}
+	assert.True(t, rbt1.IsEmpty())
+}
+
+func TestDebugHeap(t *testing.T) {
+	h1 := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+	h2 := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+	var r1, r2 recordOfHeap
+	h1.InsertNode(&r1.HeapNode)
+	assert.PanicsWithValue(t, "intrusive: HeapNode already in a container", func() { h2.InsertNode(&r1.HeapNode) })
+	assert.PanicsWithValue(t, "intrusive: HeapNode already in a container", func() { h2.PushPop(&r1.HeapNode) })
+	assert.PanicsWithValue(t, "intrusive: HeapNode in another container", func() { h2.RemoveNode(&r1.HeapNode) })
+	assert.PanicsWithValue(t, "intrusive: HeapNode in another container", func() { h2.FixNode(&r1.HeapNode) })
+	r2.Value = 1
+	assert.Equal(t, &r1.HeapNode, h1.PushPop(&r2.HeapNode))
+	assert.PanicsWithValue(t, "intrusive: HeapNode not in any container (removed already?)", func() { h1.RemoveNode(&r1.HeapNode) })
+	h1.RemoveNode(&r2.HeapNode)
+	assert.True(t, h1.IsEmpty())
+}
+
+func TestDebugHashMap(t *testing.T) {
+	hm1 := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+	hm2 := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+	var r recordOfHashMap
+	hm1.InsertNode(&r.HashMapNode, r.Value)
+	assert.PanicsWithValue(t, "intrusive: HashMapNode already in a container", func() { hm2.InsertNode(&r.HashMapNode, r.Value) })
+	assert.PanicsWithValue(t, "intrusive: HashMapNode in another container", func() { hm2.RemoveNode(&r.HashMapNode) })
+	hm1.RemoveNode(&r.HashMapNode)
+	assert.PanicsWithValue(t, "intrusive: HashMapNode not in any container (removed already?)", func() { hm1.RemoveNode(&r.HashMapNode) })
+	assert.True(t, hm1.IsEmpty())
+}
@@ -0,0 +1,237 @@
+package intrusive
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DumpAsDot writes the tree to the given writer in the DOT language of
+// Graphviz, with nodes filled in their colors (red or black) and labeled
+// by the given labeler, and then returns the first error from the writer,
+// if any.
+// Leaves are drawn as points, so that left children and right children
+// can be told apart.
+func (rbt *RBTree) DumpAsDot(w io.Writer, nodeLabeler func(*RBTreeNode) string) error {
+	d := dumper{w: w}
+	d.printf("digraph rbtree {\n")
+	d.printf("\tnode [style=filled, fontcolor=white];\n")
+
+	if root := rbt.root(); root != nil {
+		rbt.dumpNodeAsDot(&d, root, nodeLabeler)
+	}
+
+	d.printf("}\n")
+	return d.err
+}
+
+// DumpAsText writes the tree to the given writer in an indented text form,
+// one node per line in pre-order, with nodes labeled by the given labeler,
+// and then returns the first error from the writer, if any.
+// Every child is prefixed with "L: " or "R: " for its side, and every node
+// is suffixed with its color.
+func (rbt *RBTree) DumpAsText(w io.Writer, nodeLabeler func(*RBTreeNode) string) error {
+	d := dumper{w: w}
+
+	if root := rbt.root(); root != nil {
+		rbt.dumpNodeAsText(&d, root, 0, "", nodeLabeler)
+	}
+
+	return d.err
+}
+
+func (rbt *RBTree) dumpNodeAsDot(d *dumper, x *RBTreeNode, nodeLabeler func(*RBTreeNode) string) int {
+	id := d.newID()
+	d.printf("\tn%d [label=%s, fillcolor=%s];\n", id, quoteDotString(nodeLabeler(x)), x.colorName())
+
+	for _, y := range [...]*RBTreeNode{x.leftChild, x.rightChild} {
+		var childID int
+
+		if y == nil {
+			childID = d.newID()
+			d.printf("\tn%d [shape=point];\n", childID)
+		} else {
+			childID = rbt.dumpNodeAsDot(d, y, nodeLabeler)
+		}
+
+		d.printf("\tn%d -> n%d;\n", id, childID)
+	}
+
+	return id
+}
+
+func (rbt *RBTree) dumpNodeAsText(d *dumper, x *RBTreeNode, depth int, prefix string, nodeLabeler func(*RBTreeNode) string) {
+	d.printf("%s%s%s (%s)\n", indentText(depth), prefix, nodeLabeler(x), x.colorName())
+
+	if y := x.leftChild; y != nil {
+		rbt.dumpNodeAsText(d, y, depth+1, "L: ", nodeLabeler)
+	}
+
+	if y := x.rightChild; y != nil {
+		rbt.dumpNodeAsText(d, y, depth+1, "R: ", nodeLabeler)
+	}
+}
+
+func (rbtn *RBTreeNode) colorName() string {
+	if rbtn.color == rbTreeNodeRed {
+		return "red"
+	}
+
+	return "black"
+}
+
+// DumpAsDot writes the heap to the given writer in the DOT language of
+// Graphviz, laid out as a binary tree with nodes labeled by the given
+// labeler, and then returns the first error from the writer, if any.
+func (h *Heap) DumpAsDot(w io.Writer, nodeLabeler func(*HeapNode) string) error {
+	d := dumper{w: w}
+	d.printf("digraph heap {\n")
+
+	for i, x := range h.nodes {
+		d.printf("\tn%d [label=%s];\n", i, quoteDotString(nodeLabeler(x)))
+
+		if i >= 1 {
+			d.printf("\tn%d -> n%d;\n", (i-1)/2, i)
+		}
+	}
+
+	d.printf("}\n")
+	return d.err
+}
+
+// DumpAsText writes the heap to the given writer in an indented text form,
+// laid out as a binary tree with one node per line in pre-order, and then
+// returns the first error from the writer, if any.
+// Every node is prefixed with its slot in brackets and labeled by the
+// given labeler.
+func (h *Heap) DumpAsText(w io.Writer, nodeLabeler func(*HeapNode) string) error {
+	d := dumper{w: w}
+
+	if len(h.nodes) >= 1 {
+		h.dumpNodeAsText(&d, 0, 0, nodeLabeler)
+	}
+
+	return d.err
+}
+
+func (h *Heap) dumpNodeAsText(d *dumper, i int, depth int, nodeLabeler func(*HeapNode) string) {
+	d.printf("%s[%d] %s\n", indentText(depth), i, nodeLabeler(h.nodes[i]))
+
+	for j := 2*i + 1; j <= 2*i+2 && j < len(h.nodes); j++ {
+		h.dumpNodeAsText(d, j, depth+1, nodeLabeler)
+	}
+}
+
+// DumpAsDot writes the map to the given writer in the DOT language of
+// Graphviz, and then returns the first error from the writer, if any.
+// Slots are drawn as boxes in a column, each followed by the chain of its
+// nodes labeled by the given labeler, from the last inserted one.
+// A dashed edge links a slot to the slot split from it by linear hashing,
+// and the slot to split next is drawn in bold.
+func (hm *HashMap) DumpAsDot(w io.Writer, nodeLabeler func(*HashMapNode) string) error {
+	d := dumper{w: w}
+	d.printf("digraph hashmap {\n")
+	d.printf("\trankdir=LR;\n")
+	d.printf("\tnode [shape=box];\n")
+	nextSplitSlotIndex := hm.nextSplitSlotIndex()
+
+	for i := range hm.slots {
+		if i == nextSplitSlotIndex {
+			d.printf("\ts%d [label=\"slot %d\", style=bold];\n", i, i)
+		} else {
+			d.printf("\ts%d [label=\"slot %d\"];\n", i, i)
+		}
+	}
+
+	d.printf("\t{rank=same;")
+
+	for i := range hm.slots {
+		d.printf(" s%d;", i)
+	}
+
+	d.printf("}\n")
+
+	for i := range hm.slots {
+		if j := i + hm.minSlotCount(); j < len(hm.slots) {
+			d.printf("\ts%d -> s%d [style=dashed, label=split];\n", i, j)
+		}
+
+		from := fmt.Sprintf("s%d", i)
+
+		for x := hm.slots[i].lastNode; x != &hashMapNil; x = x.prev {
+			id := d.newID()
+			d.printf("\tn%d [label=%s, shape=ellipse];\n", id, quoteDotString(nodeLabeler(x)))
+			d.printf("\t%s -> n%d;\n", from, id)
+			from = fmt.Sprintf("n%d", id)
+		}
+	}
+
+	d.printf("}\n")
+	return d.err
+}
+
+// DumpAsText writes the map to the given writer in an indented text form,
+// and then returns the first error from the writer, if any.
+// The first line tells the number of slots, the number of nodes and the
+// slot to split next by linear hashing. Then every slot follows with a
+// note on its split state and with the chain of its nodes labeled by the
+// given labeler, one node per line from the last inserted one.
+func (hm *HashMap) DumpAsText(w io.Writer, nodeLabeler func(*HashMapNode) string) error {
+	d := dumper{w: w}
+	minSlotCount := hm.minSlotCount()
+	d.printf("%d slots, %d nodes, next split: slot %d\n", len(hm.slots), hm.nodeCount, hm.nextSplitSlotIndex())
+
+	for i := range hm.slots {
+		if j := i + minSlotCount; j < len(hm.slots) {
+			d.printf("slot %d (split into slot %d)\n", i, j)
+		} else if i >= minSlotCount {
+			d.printf("slot %d (split from slot %d)\n", i, i-minSlotCount)
+		} else {
+			d.printf("slot %d\n", i)
+		}
+
+		for x := hm.slots[i].lastNode; x != &hashMapNil; x = x.prev {
+			d.printf("%s%s\n", indentText(1), nodeLabeler(x))
+		}
+	}
+
+	return d.err
+}
+
+// nextSplitSlotIndex returns the index of the slot to split by the next
+// expansion of the map.
+func (hm *HashMap) nextSplitSlotIndex() int {
+	return hm.calculateLowSlotIndex(len(hm.slots))
+}
+
+// dumper writes formatted text to a writer, and keeps the first error from
+// the writer, after which it writes nothing more.
+type dumper struct {
+	w      io.Writer
+	err    error
+	lastID int
+}
+
+func (d *dumper) printf(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+func (d *dumper) newID() int {
+	id := d.lastID
+	d.lastID++
+	return id
+}
+
+func indentText(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+func quoteDotString(s string) string {
+	return "\"" + dotStringEscaper.Replace(s) + "\""
+}
+
+var dotStringEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
@@ -0,0 +1,185 @@
+package intrusive_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRBTreeDump(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var buffer bytes.Buffer
+	assert.NoError(t, rbt.DumpAsDot(&buffer, labelRBTreeNodeOfRecord))
+	assert.Equal(t, "digraph rbtree {\n\tnode [style=filled, fontcolor=white];\n}\n", buffer.String())
+	buffer.Reset()
+	assert.NoError(t, rbt.DumpAsText(&buffer, labelRBTreeNodeOfRecord))
+	assert.Equal(t, "", buffer.String())
+	for v := 1; v <= 6; v++ {
+		rbt.InsertNode(&(&recordOfRBTree{Value: v}).RBTreeNode)
+	}
+	buffer.Reset()
+	assert.NoError(t, rbt.DumpAsDot(&buffer, labelRBTreeNodeOfRecord))
+	assert.Equal(t, `digraph rbtree {
+	node [style=filled, fontcolor=white];
+	n0 [label="2", fillcolor=black];
+	n1 [label="1", fillcolor=black];
+	n2 [shape=point];
+	n1 -> n2;
+	n3 [shape=point];
+	n1 -> n3;
+	n0 -> n1;
+	n4 [label="4", fillcolor=red];
+	n5 [label="3", fillcolor=black];
+	n6 [shape=point];
+	n5 -> n6;
+	n7 [shape=point];
+	n5 -> n7;
+	n4 -> n5;
+	n8 [label="5", fillcolor=black];
+	n9 [shape=point];
+	n8 -> n9;
+	n10 [label="6", fillcolor=red];
+	n11 [shape=point];
+	n10 -> n11;
+	n12 [shape=point];
+	n10 -> n12;
+	n8 -> n10;
+	n4 -> n8;
+	n0 -> n4;
+}
+`, buffer.String())
+	buffer.Reset()
+	assert.NoError(t, rbt.DumpAsText(&buffer, labelRBTreeNodeOfRecord))
+	assert.Equal(t, `2 (black)
+  L: 1 (black)
+  R: 4 (red)
+    L: 3 (black)
+    R: 5 (black)
+      R: 6 (red)
+`, buffer.String())
+	w := &failingWriter{N: 3}
+	assert.EqualError(t, rbt.DumpAsDot(w, labelRBTreeNodeOfRecord), "write failed")
+	assert.Equal(t, 4, w.WriteCount)
+}
+
+func TestHeapDump(t *testing.T) {
+	h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+	for v := 6; v >= 1; v-- {
+		h.InsertNode(&(&recordOfHeap{Value: v}).HeapNode)
+	}
+	var buffer bytes.Buffer
+	assert.NoError(t, h.DumpAsDot(&buffer, labelHeapNodeOfRecord))
+	assert.Equal(t, `digraph heap {
+	n0 [label="1"];
+	n1 [label="3"];
+	n0 -> n1;
+	n2 [label="2"];
+	n0 -> n2;
+	n3 [label="6"];
+	n1 -> n3;
+	n4 [label="4"];
+	n1 -> n4;
+	n5 [label="5"];
+	n2 -> n5;
+}
+`, buffer.String())
+	buffer.Reset()
+	assert.NoError(t, h.DumpAsText(&buffer, labelHeapNodeOfRecord))
+	assert.Equal(t, `[0] 1
+  [1] 3
+    [3] 6
+    [4] 4
+  [2] 2
+    [5] 5
+`, buffer.String())
+	w := &failingWriter{N: 0}
+	assert.EqualError(t, h.DumpAsText(w, labelHeapNodeOfRecord), "write failed")
+	assert.Equal(t, 1, w.WriteCount)
+}
+
+func TestHashMapDump(t *testing.T) {
+	hm := new(intrusive.HashMap).Init(2, hashKey, matchHashMapNodeOfRecord)
+	for v := 0; v < 6; v++ {
+		hm.InsertNode(&(&recordOfHashMap{Value: v}).HashMapNode, v)
+	}
+	var buffer bytes.Buffer
+	assert.NoError(t, hm.DumpAsDot(&buffer, labelHashMapNodeOfRecord))
+	assert.Equal(t, `digraph hashmap {
+	rankdir=LR;
+	node [shape=box];
+	s0 [label="slot 0"];
+	s1 [label="slot 1", style=bold];
+	s2 [label="slot 2"];
+	{rank=same; s0; s1; s2;}
+	s0 -> s2 [style=dashed, label=split];
+	n0 [label="4", shape=ellipse];
+	s0 -> n0;
+	n1 [label="0", shape=ellipse];
+	n0 -> n1;
+	n2 [label="5", shape=ellipse];
+	s1 -> n2;
+	n3 [label="3", shape=ellipse];
+	n2 -> n3;
+	n4 [label="1", shape=ellipse];
+	n3 -> n4;
+	n5 [label="2", shape=ellipse];
+	s2 -> n5;
+}
+`, buffer.String())
+	buffer.Reset()
+	assert.NoError(t, hm.DumpAsText(&buffer, labelHashMapNodeOfRecord))
+	assert.Equal(t, `3 slots, 6 nodes, next split: slot 1
+slot 0 (split into slot 2)
+  4
+  0
+slot 1
+  5
+  3
+  1
+slot 2 (split from slot 0)
+  2
+`, buffer.String())
+}
+
+func TestDumpAsDotQuoting(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	rbt.InsertNode(&(&recordOfRBTree{Value: 1}).RBTreeNode)
+	var buffer bytes.Buffer
+	assert.NoError(t, rbt.DumpAsDot(&buffer, func(*intrusive.RBTreeNode) string {
+		return "a \"b\"\n\\c"
+	}))
+	assert.Contains(t, buffer.String(), `n0 [label="a \"b\"\n\\c", fillcolor=black];`)
+}
+
+func labelRBTreeNodeOfRecord(node *intrusive.RBTreeNode) string {
+	return fmt.Sprint((*recordOfRBTree)(node.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode))).Value)
+}
+
+func labelHeapNodeOfRecord(node *intrusive.HeapNode) string {
+	return fmt.Sprint((*recordOfHeap)(node.GetContainer(unsafe.Offsetof(recordOfHeap{}.HeapNode))).Value)
+}
+
+func labelHashMapNodeOfRecord(node *intrusive.HashMapNode) string {
+	return fmt.Sprint((*recordOfHashMap)(node.GetContainer(unsafe.Offsetof(recordOfHashMap{}.HashMapNode))).Value)
+}
+
+// failingWriter fails every write after the first N ones.
+type failingWriter struct {
+	N          int
+	WriteCount int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	fw.WriteCount++
+
+	if fw.WriteCount > fw.N {
+		return 0, errors.New("write failed")
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package intrusive_test
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/roy2220/intrusive"
+)
+
+func ExampleIntervalTree() {
+	type Record struct {
+		IntervalTreeNode intrusive.IntervalTreeNode
+		Start, End       int64
+	}
+
+	rs := []Record{
+		{Start: 7, End: 9},
+		{Start: 1, End: 5},
+		{Start: 12, End: 15},
+		{Start: 2, End: 8},
+		{Start: 10, End: 11},
+	}
+
+	ivt := new(intrusive.IntervalTree).Init()
+
+	for i := range rs {
+		r := &rs[i]
+		ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+	}
+
+	for it := ivt.ForeachOverlap(4, 11); !it.IsAtEnd(); it.Advance() {
+		r := (*Record)(it.Node().GetContainer(unsafe.Offsetof(Record{}.IntervalTreeNode)))
+		fmt.Printf("[%v,%v),", r.Start, r.End)
+	}
+	fmt.Println("")
+
+	ivt.RemoveNode(&rs[3].IntervalTreeNode)
+
+	for it := ivt.ForeachStab(7); !it.IsAtEnd(); it.Advance() {
+		r := (*Record)(it.Node().GetContainer(unsafe.Offsetof(Record{}.IntervalTreeNode)))
+		fmt.Printf("[%v,%v),", r.Start, r.End)
+	}
+	fmt.Println("")
+	// Output:
+	// [1,5),[2,8),[7,9),[10,11),
+	// [7,9),
+}
@@ -27,8 +27,8 @@ func ExampleList() {
 	l.AppendNode(&rs[1].ListNode)
 	l.PrependNode(&rs[2].ListNode)
 	l.PrependNode(&rs[3].ListNode)
-	rs[4].ListNode.InsertBefore(l.Head())
-	rs[5].ListNode.InsertAfter(&rs[1].ListNode)
+	l.InsertNodeBefore(&rs[4].ListNode, l.Head())
+	l.InsertNodeAfter(&rs[5].ListNode, &rs[1].ListNode)
 
 	for it := l.Foreach(); !it.IsAtEnd(); it.Advance() {
 		r := (*Record)(it.Node().GetContainer(unsafe.Offsetof(Record{}.ListNode)))
@@ -36,10 +36,10 @@ func ExampleList() {
 	}
 	fmt.Println("")
 
-	l.Head().Remove()
-	l.Tail().Remove()
-	rs[2].ListNode.Remove()
-	rs[0].ListNode.Remove()
+	l.RemoveNode(l.Head())
+	l.RemoveNode(l.Tail())
+	l.RemoveNode(&rs[2].ListNode)
+	l.RemoveNode(&rs[0].ListNode)
 
 	for it := l.ForeachReverse(); !it.IsAtEnd(); it.Advance() {
 		r := (*Record)(it.Node().GetContainer(unsafe.Offsetof(Record{}.ListNode)))
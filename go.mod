@@ -1,8 +1,12 @@
 module github.com/roy2220/intrusive
 
-go 1.13
+go 1.23
+
+require github.com/stretchr/testify v1.5.1
 
 require (
-	github.com/stretchr/testify v1.5.1
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/lint v0.0.0-20200302205851-738671d3881b // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
@@ -1,6 +1,8 @@
 package intrusive
 
 import (
+	"fmt"
+	"iter"
 	"math"
 	"unsafe"
 )
@@ -13,6 +15,7 @@ type HashMap struct {
 	slots             []hashMapSlot
 	minSlotCountShift int
 	nodeCount         int
+	iterationCount    int
 }
 
 // Init initializes the map and then returns the map.
@@ -31,6 +34,7 @@ func (hm *HashMap) Init(maxLoadFactor float64, keyHasher HashMapKeyHasher, nodeM
 // InsertNode inserts the given node with the given key
 // to the map.
 func (hm *HashMap) InsertNode(node *HashMapNode, key interface{}) {
+	node.owner.attach(unsafe.Pointer(hm), "HashMapNode")
 	keyHash := hm.keyHasher(key)
 	hm.getSlot(keyHash).AppendNode(node)
 	node.keyHash = keyHash
@@ -38,11 +42,19 @@ func (hm *HashMap) InsertNode(node *HashMapNode, key interface{}) {
 	hm.maybeExpand()
 }
 
-// RemoveNode removes the given node from the map.
+// RemoveNode removes the given node from the map and then resets
+// the node.
 func (hm *HashMap) RemoveNode(node *HashMapNode) {
+	node.owner.check(unsafe.Pointer(hm), "HashMapNode")
 	hm.getSlot(node.keyHash).RemoveNode(node)
+	*node = HashMapNode{}
 	hm.nodeCount--
-	hm.maybeShrink()
+
+	// Shrinking merges slots, which would make iterations in progress
+	// miss nodes, so it's put off until no iteration is in progress.
+	if hm.iterationCount == 0 {
+		hm.maybeShrink()
+	}
 }
 
 // FindNode finds a node with the given key in the map and
@@ -53,11 +65,77 @@ func (hm *HashMap) FindNode(key interface{}) (*HashMapNode, bool) {
 	return hm.getSlot(keyHash).FindNode(keyHash, hm.nodeMatcher, key)
 }
 
+// Clear removes all nodes from the map in O(n) time, resetting each of
+// the nodes to a zero value.
+// The given callback, if not nil, is called with each node,
+// after the node has been reset.
+func (hm *HashMap) Clear(onNode func(*HashMapNode)) {
+	slots := hm.slots
+	hm.slots = slots[:1]
+	hm.minSlotCountShift = 0
+	hm.nodeCount = 0
+
+	for i := range slots {
+		node := slots[i].lastNode
+		slots[i] = emptyHashMapSlot
+
+		for node != &hashMapNil {
+			prevNode := node.prev
+			*node = HashMapNode{}
+
+			if onNode != nil {
+				onNode(node)
+			}
+
+			node = prevNode
+		}
+	}
+}
+
 // Foreach returns an iterator over all nodes in the map.
 func (hm *HashMap) Foreach() *HashMapIterator {
 	return new(HashMapIterator).Init(hm)
 }
 
+// All returns an iterator, for use with range-over-func, over all nodes
+// in the map.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached and the map doesn't shrink until the
+// iteration ends.
+func (hm *HashMap) All() iter.Seq[*HashMapNode] {
+	return func(yield func(*HashMapNode) bool) {
+		hm.iterationCount++
+		defer hm.endIteration()
+		var it HashMapIterator
+
+		for it.Init(hm); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
+// AllMatching returns an iterator, for use with range-over-func, over all
+// nodes with the given key in the map.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+func (hm *HashMap) AllMatching(key interface{}) iter.Seq[*HashMapNode] {
+	return func(yield func(*HashMapNode) bool) {
+		keyHash := hm.keyHasher(key)
+
+		for node := hm.getSlot(keyHash).lastNode; node != &hashMapNil; {
+			prevNode := node.prev
+
+			if node.keyHash == keyHash && hm.nodeMatcher(node, key) && !yield(node) {
+				return
+			}
+
+			node = prevNode
+		}
+	}
+}
+
 // IsEmpty indicates whether the map is empty.
 func (hm *HashMap) IsEmpty() bool {
 	return hm.NumberOfNodes() == 0
@@ -68,6 +146,59 @@ func (hm *HashMap) NumberOfNodes() int {
 	return hm.nodeCount
 }
 
+// Validate checks the integrity of the map and then returns an error
+// telling where the first violation is found, if any.
+// Every node must be in the slot located by its key hash, and the number
+// of nodes in all slots must match the number of nodes of the map.
+// Nodes in a slot are numbered from the last inserted one starting with 0.
+func (hm *HashMap) Validate() error {
+	if n := len(hm.slots); n < hm.minSlotCount() || n >= hm.maxSlotCountPlusOne() {
+		return fmt.Errorf("intrusive: hash map: number of slots is %d, want within [%d, %d)", n, hm.minSlotCount(), hm.maxSlotCountPlusOne())
+	}
+
+	nodeCount := 0
+
+	for i := range hm.slots {
+		j := 0
+
+		for x := hm.slots[i].lastNode; x != &hashMapNil; x = x.prev {
+			if x == nil {
+				return fmt.Errorf("intrusive: hash map slot %d, node #%d: nil (broken chain)", i, j)
+			}
+
+			// Bounding the walk keeps a cycle in the chain from looping forever.
+			if nodeCount == hm.nodeCount {
+				return fmt.Errorf("intrusive: hash map slot %d, node #%d: more nodes than %d", i, j, hm.nodeCount)
+			}
+
+			if k := hm.locateSlot(x.keyHash); k != i {
+				return fmt.Errorf("intrusive: hash map slot %d, node #%d: belongs to slot %d", i, j, k)
+			}
+
+			if debugMode && x.owner.getContainer() != unsafe.Pointer(hm) {
+				return fmt.Errorf("intrusive: hash map slot %d, node #%d: not owned by the map", i, j)
+			}
+
+			nodeCount++
+			j++
+		}
+	}
+
+	if nodeCount != hm.nodeCount {
+		return fmt.Errorf("intrusive: hash map: number of nodes is %d, want %d", hm.nodeCount, nodeCount)
+	}
+
+	return nil
+}
+
+func (hm *HashMap) endIteration() {
+	hm.iterationCount--
+
+	if hm.iterationCount == 0 {
+		hm.maybeShrink()
+	}
+}
+
 func (hm *HashMap) getSlot(keyHash uint64) *hashMapSlot {
 	slotIndex := hm.locateSlot(keyHash)
 	return &hm.slots[slotIndex]
@@ -152,6 +283,7 @@ type HashMapNodeMatcher func(hmn *HashMapNode, key interface{}) bool
 
 // HashMapNode represents a node in a hash map.
 type HashMapNode struct {
+	owner   nodeOwner
 	prev    *HashMapNode
 	keyHash uint64
 }
@@ -163,6 +295,8 @@ func (hmn *HashMapNode) GetContainer(offset uintptr) unsafe.Pointer {
 }
 
 // IsReset indicates whether the node is reset (with a zero value).
+// As a node is reset once removed from a map, it also indicates whether
+// the node is not in any map.
 func (hmn *HashMapNode) IsReset() bool {
 	return hmn.prev == nil
 }
@@ -95,7 +95,9 @@ func TestHashMapRemoveNode(t *testing.T) {
 		assert.Equal(t, len(rs), hm.NumberOfNodes())
 		for _, v := range tt.In {
 			r := &rs[v-1]
+			assert.False(t, r.HashMapNode.IsReset(), "case %d", i)
 			hm.RemoveNode(&r.HashMapNode)
+			assert.True(t, r.HashMapNode.IsReset(), "case %d", i)
 		}
 		assert.Equal(t, tt.Out, dumpRecordHashMap(hm), "case %d", i)
 	}
@@ -169,6 +171,7 @@ func TestHashMap(t *testing.T) {
 	for j := range removedRecordIndexes {
 		hm.InsertNode(&rs[j].HashMapNode, rs[j].Value)
 	}
+	assert.NoError(t, hm.Validate())
 	for i := range rs {
 		r := &rs[i]
 		hmn, ok := hm.FindNode(r.Value)
@@ -183,6 +186,155 @@ func TestHashMap(t *testing.T) {
 	assert.True(t, hm.IsEmpty())
 }
 
+func TestHashMapClear(t *testing.T) {
+	for i, n := range []int{0, 1, 1000} {
+		hm := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+		rs := make([]recordOfHashMap, n)
+		for j := range rs {
+			rs[j].Value = j
+			hm.InsertNode(&rs[j].HashMapNode, j)
+		}
+		var values []int
+		hm.Clear(func(hmn *intrusive.HashMapNode) {
+			assert.True(t, hmn.IsReset(), "case %d", i)
+			r := (*recordOfHashMap)(hmn.GetContainer(unsafe.Offsetof(recordOfHashMap{}.HashMapNode)))
+			values = append(values, r.Value)
+		})
+		assert.True(t, hm.IsEmpty(), "case %d", i)
+		assert.Equal(t, 0, hm.NumberOfNodes(), "case %d", i)
+		assert.NoError(t, hm.Validate(), "case %d", i)
+		sort.Ints(values)
+		assert.Len(t, values, n, "case %d", i)
+		for j := range rs {
+			assert.True(t, rs[j].HashMapNode.IsReset(), "case %d", i)
+			assert.Equal(t, j, values[j], "case %d", i)
+		}
+		for j := range rs {
+			hm.InsertNode(&rs[j].HashMapNode, j)
+		}
+		for j := range rs {
+			hmn, ok := hm.FindNode(j)
+			if assert.True(t, ok, "case %d", i) {
+				assert.Equal(t, &rs[j].HashMapNode, hmn, "case %d", i)
+			}
+		}
+		assert.Equal(t, n, hm.NumberOfNodes(), "case %d", i)
+	}
+}
+
+func TestHashMapValidate(t *testing.T) {
+	for i, tt := range []struct {
+		In  func([]recordOfHashMap)
+		Err string
+	}{
+		{
+			In:  func(rs []recordOfHashMap) {},
+			Err: "",
+		},
+		{
+			In: func(rs []recordOfHashMap) {
+				*keyHashOfHashMapNode(&rs[3].HashMapNode) = 4
+			},
+			Err: "intrusive: hash map slot 3, node #0: belongs to slot 4",
+		},
+	} {
+		hm := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+		assert.NoError(t, hm.Validate(), "case %d", i)
+		rs := make([]recordOfHashMap, 6)
+		for j := range rs {
+			rs[j].Value = j
+			hm.InsertNode(&rs[j].HashMapNode, j)
+		}
+		tt.In(rs)
+		if tt.Err == "" {
+			assert.NoError(t, hm.Validate(), "case %d", i)
+		} else {
+			assert.EqualError(t, hm.Validate(), tt.Err, "case %d", i)
+		}
+	}
+}
+
+func TestHashMapAll(t *testing.T) {
+	hm := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+	var rs [1000]recordOfHashMap
+	for i := range rs {
+		rs[i].Value = i
+		hm.InsertNode(&rs[i].HashMapNode, i)
+	}
+	n := 0
+	for range hm.All() {
+		if n++; n == 10 {
+			break
+		}
+	}
+	assert.Equal(t, 10, n)
+	visited := make(map[*intrusive.HashMapNode]bool, len(rs))
+	for hmn := range hm.All() {
+		assert.False(t, visited[hmn])
+		visited[hmn] = true
+		r := (*recordOfHashMap)(hmn.GetContainer(unsafe.Offsetof(recordOfHashMap{}.HashMapNode)))
+		if r.Value%10 != 0 {
+			hm.RemoveNode(hmn)
+		}
+	}
+	assert.Len(t, visited, len(rs))
+	assert.Equal(t, len(rs)/10, hm.NumberOfNodes())
+	assert.NoError(t, hm.Validate())
+	for i := range rs {
+		if i%10 != 0 {
+			hm.InsertNode(&rs[i].HashMapNode, i)
+		}
+	}
+	visited = make(map[*intrusive.HashMapNode]bool, len(rs))
+	for hmn := range hm.All() {
+		visited[hmn] = true
+		hm.RemoveNode(hmn)
+	}
+	assert.Len(t, visited, len(rs))
+	assert.True(t, hm.IsEmpty())
+	assert.NoError(t, hm.Validate())
+}
+
+func TestHashMapAllMatching(t *testing.T) {
+	hm := new(intrusive.HashMap).Init(0, hashKey, matchHashMapNodeOfRecord)
+	rs := make([]recordOfHashMap, 100)
+	for i := range rs {
+		rs[i].Value = i % 10
+		hm.InsertNode(&rs[i].HashMapNode, i%10)
+	}
+	for i, tt := range []struct {
+		Key    int
+		Remove bool
+		Count  int
+	}{
+		{Key: 3, Count: 10},
+		{Key: 3, Remove: true, Count: 10},
+		{Key: 3, Count: 0},
+		{Key: 7, Remove: true, Count: 10},
+		{Key: 99, Count: 0},
+	} {
+		n := 0
+		for hmn := range hm.AllMatching(tt.Key) {
+			r := (*recordOfHashMap)(hmn.GetContainer(unsafe.Offsetof(recordOfHashMap{}.HashMapNode)))
+			assert.Equal(t, tt.Key, r.Value, "case %d", i)
+			if tt.Remove {
+				hm.RemoveNode(hmn)
+			}
+			n++
+		}
+		assert.Equal(t, tt.Count, n, "case %d", i)
+		assert.NoError(t, hm.Validate(), "case %d", i)
+	}
+	assert.Equal(t, 80, hm.NumberOfNodes())
+	n := 0
+	for range hm.AllMatching(1) {
+		if n++; n == 4 {
+			break
+		}
+	}
+	assert.Equal(t, 4, n)
+}
+
 type recordOfHashMap struct {
 	Value       int
 	HashMapNode intrusive.HashMapNode
@@ -222,3 +374,10 @@ func dumpRecordHashMap(hashMap *intrusive.HashMap) string {
 
 	return ""
 }
+
+// keyHashOfHashMapNode returns the key hash of the given node, which is
+// the last field of HashMapNode, for corrupting hash maps.
+func keyHashOfHashMapNode(hashMapNode *intrusive.HashMapNode) *uint64 {
+	offset := unsafe.Sizeof(*hashMapNode) - unsafe.Sizeof(uint64(0))
+	return (*uint64)(unsafe.Pointer(uintptr(unsafe.Pointer(hashMapNode)) + offset))
+}
@@ -1,6 +1,10 @@
 package intrusive
 
-import "unsafe"
+import (
+	"fmt"
+	"iter"
+	"unsafe"
+)
 
 // Heap presents a binary heap.
 type Heap struct {
@@ -17,18 +21,105 @@ func (h *Heap) Init(nodeOrderer HeapNodeOrderer, initialCapacity int) *Heap {
 
 // InsertNode inserts the given node to the heap.
 func (h *Heap) InsertNode(node *HeapNode) {
+	node.owner.attach(unsafe.Pointer(h), "HeapNode")
 	nodeIndex := len(h.nodes)
 	h.nodes = append(h.nodes, nil)
 	h.siftUp(node, nodeIndex)
 }
 
-// RemoveNode removes the given node from the heap.
+// RemoveNode removes the given node from the heap and then resets
+// the node.
 func (h *Heap) RemoveNode(node *HeapNode) {
+	node.owner.check(unsafe.Pointer(h), "HeapNode")
 	lastNode := h.removeLastNode()
 
 	if node != lastNode {
 		h.replaceNode(node, lastNode, node.index())
 	}
+
+	*node = HeapNode{}
+}
+
+// FixNode restores the order of the heap after the key of the given
+// node has changed, moving the node up or down as needed.
+// The given node must be in the heap.
+func (h *Heap) FixNode(node *HeapNode) {
+	node.owner.check(unsafe.Pointer(h), "HeapNode")
+	nodeIndex := node.index()
+
+	if nodeIndex >= 1 && !h.nodeOrderer(h.nodes[(nodeIndex-1)/2], node) {
+		h.siftUp(node, nodeIndex)
+	} else {
+		h.siftDown(node, nodeIndex)
+	}
+}
+
+// PopTop removes the node with the minimum key from the heap and
+// then returns the node.
+// If the heap is empty, it returns false.
+func (h *Heap) PopTop() (*HeapNode, bool) {
+	top, ok := h.GetTop()
+
+	if !ok {
+		return nil, false
+	}
+
+	h.RemoveNode(top)
+	return top, true
+}
+
+// PushPop inserts the given node to the heap and then removes the
+// node with the minimum key from the heap, returning the removed node.
+// It's more efficient than InsertNode followed by PopTop, and the given
+// node is returned immediately if its key is not greater than the
+// minimum key in the heap.
+func (h *Heap) PushPop(node *HeapNode) *HeapNode {
+	node.owner.attach(unsafe.Pointer(h), "HeapNode")
+
+	if h.IsEmpty() || h.nodeOrderer(node, h.nodes[0]) {
+		node.owner.detach()
+		return node
+	}
+
+	top := h.nodes[0]
+	h.siftDown(node, 0)
+	*top = HeapNode{}
+	return top
+}
+
+// ReplaceTop removes the node with the minimum key from the heap and
+// then inserts the given node to the heap, returning the removed node.
+// It's more efficient than PopTop followed by InsertNode.
+// If the heap is empty, it just inserts the given node and returns false.
+func (h *Heap) ReplaceTop(node *HeapNode) (*HeapNode, bool) {
+	if h.IsEmpty() {
+		h.InsertNode(node)
+		return nil, false
+	}
+
+	node.owner.attach(unsafe.Pointer(h), "HeapNode")
+	top := h.nodes[0]
+	h.siftDown(node, 0)
+	*top = HeapNode{}
+	return top, true
+}
+
+// Clear removes all nodes from the heap in O(n) time, resetting each of
+// the nodes to a zero value.
+// The given callback, if not nil, is called with each node,
+// after the node has been reset.
+func (h *Heap) Clear(onNode func(*HeapNode)) {
+	nodes := h.nodes
+	h.nodes = nodes[:0]
+
+	for i, node := range nodes {
+		nodes[i] = nil
+		*node = HeapNode{}
+
+		if onNode != nil {
+			onNode(node)
+		}
+	}
 }
 
 // GetTop returns the node with the minimum key in the heap.
@@ -46,6 +137,49 @@ func (h *Heap) Foreach() *HeapIterator {
 	return new(HeapIterator).Init(h)
 }
 
+// All returns an iterator, for use with range-over-func, over all nodes
+// in the heap in no particular order.
+// It's safe to remove the yielded node during the iteration.
+func (h *Heap) All() iter.Seq[*HeapNode] {
+	return func(yield func(*HeapNode) bool) {
+		// Nodes are visited from the last slot to the first one, so that
+		// the unvisited nodes always stay in the slots up to the current one.
+		// Removing the yielded node moves the last node, which is visited,
+		// to the slot of the removed node, where the node may be sifted up
+		// to a slot before the current one, and then must be skipped there.
+		var siftedUpNodes map[*HeapNode]struct{}
+
+		for i := len(h.nodes) - 1; i >= 0; {
+			x := h.nodes[i]
+
+			if _, ok := siftedUpNodes[x]; ok {
+				delete(siftedUpNodes, x)
+				i--
+				continue
+			}
+
+			y := h.nodes[len(h.nodes)-1]
+
+			if !yield(x) {
+				return
+			}
+
+			if x.IsReset() && x != y && y.index() < i {
+				// The node sifted up has been replaced by an unvisited
+				// node at the current slot, which is to visit next.
+				if siftedUpNodes == nil {
+					siftedUpNodes = make(map[*HeapNode]struct{})
+				}
+
+				siftedUpNodes[y] = struct{}{}
+				continue
+			}
+
+			i--
+		}
+	}
+}
+
 // IsEmpty indicates whether the heap is empty.
 func (h *Heap) IsEmpty() bool {
 	return h.NumberOfNodes() == 0
@@ -56,6 +190,34 @@ func (h *Heap) NumberOfNodes() int {
 	return len(h.nodes)
 }
 
+// Validate checks the integrity of the heap and then returns an error
+// telling where the first violation is found, if any.
+// The number stored in every node must match the slot of the node, and
+// no node may be less than its parent.
+func (h *Heap) Validate() error {
+	for i, x := range h.nodes {
+		if x.index() != i {
+			return fmt.Errorf("intrusive: heap node at slot %d: number is %d, want %d", i, x.number, i+1)
+		}
+
+		if debugMode && x.owner.getContainer() != unsafe.Pointer(h) {
+			return fmt.Errorf("intrusive: heap node at slot %d: not owned by the heap", i)
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		j := (i - 1) / 2
+
+		if y := h.nodes[j]; h.nodeOrderer(x, y) && !h.nodeOrderer(y, x) {
+			return fmt.Errorf("intrusive: heap node at slot %d: less than its parent at slot %d", i, j)
+		}
+	}
+
+	return nil
+}
+
 func (h *Heap) siftUp(x *HeapNode, i int) {
 	for {
 		if i == 0 {
@@ -140,6 +302,7 @@ type HeapNodeOrderer func(hn1 *HeapNode, hn2 *HeapNode) bool
 
 // HeapNode represents a node in a binary heap.
 type HeapNode struct {
+	owner  nodeOwner
 	number int
 }
 
@@ -150,6 +313,8 @@ func (hn *HeapNode) GetContainer(offset uintptr) unsafe.Pointer {
 }
 
 // IsReset indicates whether the node is reset (with a zero value).
+// As a node is reset once removed from a heap, it also indicates whether
+// the node is not in any heap.
 func (hn *HeapNode) IsReset() bool {
 	return hn.number == 0
 }
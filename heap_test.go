@@ -98,12 +98,164 @@ func TestHeapRemoveNode(t *testing.T) {
 		assert.True(t, ok)
 		for _, v := range tt.In {
 			r := &rs[v-1]
+			assert.False(t, r.HeapNode.IsReset(), "case %d", i)
 			h.RemoveNode(&r.HeapNode)
+			assert.True(t, r.HeapNode.IsReset(), "case %d", i)
 		}
 		assert.Equal(t, tt.Out, dumpRecordHeap(h), "case %d", i)
 	}
 }
 
+func TestHeapFixNode(t *testing.T) {
+	for i, tt := range []struct {
+		In  [][2]int
+		Out string
+	}{
+		{
+			In:  [][2]int{},
+			Out: "1,2,3,4,5,6",
+		},
+		{
+			In:  [][2]int{{6, 0}},
+			Out: "0,1,2,3,4,5",
+		},
+		{
+			In:  [][2]int{{1, 7}},
+			Out: "2,3,4,5,6,7",
+		},
+		{
+			In:  [][2]int{{3, 10}, {5, -1}, {2, 2}},
+			Out: "-1,1,2,4,6,10",
+		},
+		{
+			In:  [][2]int{{1, 6}, {6, 1}},
+			Out: "1,2,3,4,5,6",
+		},
+	} {
+		h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 6)
+		var rs [6]recordOfHeap
+		for i := range rs {
+			r := &rs[i]
+			r.Value = i + 1
+			h.InsertNode(&r.HeapNode)
+		}
+		for _, vv := range tt.In {
+			r := &rs[vv[0]-1]
+			r.Value = vv[1]
+			h.FixNode(&r.HeapNode)
+		}
+		assert.Equal(t, tt.Out, dumpRecordHeap(h), "case %d", i)
+	}
+}
+
+func TestHeapPopTop(t *testing.T) {
+	h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+	_, ok := h.PopTop()
+	assert.False(t, ok)
+	var rs [6]recordOfHeap
+	for i := range rs {
+		r := &rs[i]
+		r.Value = len(rs) - i
+		h.InsertNode(&r.HeapNode)
+	}
+	for v := 1; v <= len(rs); v++ {
+		ht, ok := h.PopTop()
+		if assert.True(t, ok) {
+			assert.True(t, ht.IsReset())
+			r := (*recordOfHeap)(ht.GetContainer(unsafe.Offsetof(recordOfHeap{}.HeapNode)))
+			assert.Equal(t, v, r.Value)
+		}
+	}
+	assert.True(t, h.IsEmpty())
+}
+
+func TestHeapClear(t *testing.T) {
+	for i, n := range []int{0, 1, 100} {
+		h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+		rs := make([]recordOfHeap, n)
+		for j := range rs {
+			rs[j].Value = rand.Intn(n)
+			h.InsertNode(&rs[j].HeapNode)
+		}
+		m := 0
+		h.Clear(func(hn *intrusive.HeapNode) {
+			assert.True(t, hn.IsReset(), "case %d", i)
+			m++
+		})
+		assert.Equal(t, n, m, "case %d", i)
+		assert.True(t, h.IsEmpty(), "case %d", i)
+		for j := range rs {
+			assert.True(t, rs[j].HeapNode.IsReset(), "case %d", i)
+		}
+		for j := range rs {
+			rs[j].Value = n - j
+			h.InsertNode(&rs[j].HeapNode)
+		}
+		for v := 1; v <= n; v++ {
+			ht, _ := h.PopTop()
+			r := (*recordOfHeap)(ht.GetContainer(unsafe.Offsetof(recordOfHeap{}.HeapNode)))
+			assert.Equal(t, v, r.Value, "case %d", i)
+		}
+	}
+}
+
+func TestHeapPushPopReplaceTop(t *testing.T) {
+	for i, tt := range []struct {
+		In          []int
+		IsReplacing bool
+		Out         string
+		Rest        string
+	}{
+		{
+			In:   []int{0, 3, 7, 5},
+			Out:  "0,1,2,3",
+			Rest: "3,4,5,5,6,7",
+		},
+		{
+			In:          []int{0, 3, 7, 5},
+			IsReplacing: true,
+			Out:         "1,0,2,3",
+			Rest:        "3,4,5,5,6,7",
+		},
+		{
+			In:   []int{9, 9, 9},
+			Out:  "1,2,3",
+			Rest: "4,5,6,9,9,9",
+		},
+	} {
+		h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 6)
+		var rs [6]recordOfHeap
+		for i := range rs {
+			r := &rs[i]
+			r.Value = i + 1
+			h.InsertNode(&r.HeapNode)
+		}
+		var buffer bytes.Buffer
+		for _, v := range tt.In {
+			r := &recordOfHeap{Value: v}
+			var ht *intrusive.HeapNode
+			if tt.IsReplacing {
+				ht, _ = h.ReplaceTop(&r.HeapNode)
+			} else {
+				ht = h.PushPop(&r.HeapNode)
+			}
+			assert.True(t, ht.IsReset(), "case %d", i)
+			r = (*recordOfHeap)(ht.GetContainer(unsafe.Offsetof(recordOfHeap{}.HeapNode)))
+			fmt.Fprintf(&buffer, "%v,", r.Value)
+		}
+		buffer.Truncate(buffer.Len() - 1)
+		assert.Equal(t, tt.Out, buffer.String(), "case %d", i)
+		assert.Equal(t, tt.Rest, dumpRecordHeap(h), "case %d", i)
+	}
+	h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+	r := recordOfHeap{Value: 1}
+	_, ok := h.ReplaceTop(&r.HeapNode)
+	assert.False(t, ok)
+	assert.Equal(t, "1", dumpRecordHeap(h))
+	assert.Equal(t, &r.HeapNode, h.PushPop(&r.HeapNode))
+	assert.True(t, h.IsEmpty())
+}
+
 func TestHeap(t *testing.T) {
 	h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
 	var rs [100000]recordOfHeap
@@ -131,6 +283,7 @@ func TestHeap(t *testing.T) {
 	for j := range removedRecordIndexes {
 		h.InsertNode(&rs[j].HeapNode)
 	}
+	assert.NoError(t, h.Validate())
 	for it := h.Foreach(); !it.IsAtEnd(); it.Advance() {
 		r := (*recordOfHeap)(it.Node().GetContainer(unsafe.Offsetof(recordOfHeap{}.HeapNode)))
 		assert.GreaterOrEqual(t, r.Value, 1)
@@ -150,6 +303,75 @@ func TestHeap(t *testing.T) {
 	assert.True(t, h.IsEmpty())
 }
 
+func TestHeapValidate(t *testing.T) {
+	for i, tt := range []struct {
+		In  func([]recordOfHeap)
+		Err string
+	}{
+		{
+			In:  func(rs []recordOfHeap) {},
+			Err: "",
+		},
+		{
+			In: func(rs []recordOfHeap) {
+				rs[4].Value = 0
+			},
+			Err: "intrusive: heap node at slot 4: less than its parent at slot 1",
+		},
+		{
+			In: func(rs []recordOfHeap) {
+				*numberOfHeapNode(&rs[2].HeapNode) = 1
+			},
+			Err: "intrusive: heap node at slot 2: number is 1, want 3",
+		},
+	} {
+		h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+		assert.NoError(t, h.Validate(), "case %d", i)
+		rs := make([]recordOfHeap, 6)
+		for j := range rs {
+			rs[j].Value = j + 1
+			h.InsertNode(&rs[j].HeapNode)
+		}
+		tt.In(rs)
+		if tt.Err == "" {
+			assert.NoError(t, h.Validate(), "case %d", i)
+		} else {
+			assert.EqualError(t, h.Validate(), tt.Err, "case %d", i)
+		}
+	}
+}
+
+func TestHeapAll(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		h := new(intrusive.Heap).Init(orderHeapNodeOfRecord, 0)
+		rs := make([]recordOfHeap, rand.Intn(100))
+		for j := range rs {
+			rs[j].Value = rand.Intn(50)
+			h.InsertNode(&rs[j].HeapNode)
+		}
+		visited := make(map[*intrusive.HeapNode]bool, len(rs))
+		n := len(rs)
+		for hn := range h.All() {
+			assert.False(t, visited[hn], "case %d", i)
+			visited[hn] = true
+			if rand.Intn(2) == 0 {
+				h.RemoveNode(hn)
+				n--
+			}
+		}
+		assert.Len(t, visited, len(rs), "case %d", i)
+		assert.Equal(t, n, h.NumberOfNodes(), "case %d", i)
+		assert.NoError(t, h.Validate(), "case %d", i)
+		n = 0
+		for range h.All() {
+			if n++; n == 3 {
+				break
+			}
+		}
+		assert.LessOrEqual(t, n, 3, "case %d", i)
+	}
+}
+
 type recordOfHeap struct {
 	Value    int
 	HeapNode intrusive.HeapNode
@@ -182,3 +404,10 @@ func dumpRecordHeap(heap *intrusive.Heap) string {
 
 	return ""
 }
+
+// numberOfHeapNode returns the number of the given node, which is the last
+// field of HeapNode, for corrupting heaps.
+func numberOfHeapNode(heapNode *intrusive.HeapNode) *int {
+	offset := unsafe.Sizeof(*heapNode) - unsafe.Sizeof(0)
+	return (*int)(unsafe.Pointer(uintptr(unsafe.Pointer(heapNode)) + offset))
+}
@@ -0,0 +1,297 @@
+package intrusive
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// IntervalTree presents an interval tree of half-open intervals [start, end),
+// which is a red-black tree ordered by the starts of intervals and
+// augmented with the maximum end of intervals in each subtree.
+type IntervalTree struct {
+	rbt RBTree
+}
+
+// Init initializes the tree and then returns the tree.
+func (ivt *IntervalTree) Init() *IntervalTree {
+	ivt.rbt.Init(orderIntervalTreeNode, compareIntervalTreeNode).SetNodeAugmenter(augmentIntervalTreeNode)
+	return ivt
+}
+
+// InsertNode inserts the given node with the given interval [start, end)
+// to the tree.
+func (ivt *IntervalTree) InsertNode(node *IntervalTreeNode, start int64, end int64) {
+	node.start = start
+	node.end = end
+	node.maxEnd = end
+	ivt.rbt.InsertNode(&node.rbTreeNode)
+}
+
+// RemoveNode removes the given node from the tree and then resets
+// the node.
+func (ivt *IntervalTree) RemoveNode(node *IntervalTreeNode) {
+	ivt.rbt.RemoveNode(&node.rbTreeNode)
+}
+
+// FindFirstOverlap finds the node, with the minimum start, whose interval
+// overlaps the given interval [start, end) in the tree and then returns
+// the node.
+// If no such node exists, it returns false.
+func (ivt *IntervalTree) FindFirstOverlap(start int64, end int64) (*IntervalTreeNode, bool) {
+	x := ivt.findFirstOverlap(ivt.rbt.root(), start, end)
+
+	if x.isNull(&ivt.rbt) {
+		return nil, false
+	}
+
+	return intervalTreeNodeOf(x), true
+}
+
+// ForeachOverlap returns an iterator over all nodes whose intervals
+// overlap the given interval [start, end) in the tree in order of starts.
+func (ivt *IntervalTree) ForeachOverlap(start int64, end int64) *IntervalTreeIterator {
+	return new(IntervalTreeIterator).Init(ivt, start, end)
+}
+
+// ForeachStab returns an iterator over all nodes whose intervals
+// contain the given point in the tree in order of starts.
+func (ivt *IntervalTree) ForeachStab(point int64) *IntervalTreeIterator {
+	if point == math.MaxInt64 {
+		// point+1 would overflow, and no interval [start, end) with
+		// end <= math.MaxInt64 contains the point anyway.
+		return new(IntervalTreeIterator).Init(ivt, point, point)
+	}
+
+	return new(IntervalTreeIterator).Init(ivt, point, point+1)
+}
+
+// IsEmpty indicates whether the tree is empty.
+func (ivt *IntervalTree) IsEmpty() bool {
+	return ivt.rbt.IsEmpty()
+}
+
+// NumberOfNodes returns the number of nodes in the tree.
+func (ivt *IntervalTree) NumberOfNodes() int {
+	return ivt.rbt.NumberOfNodes()
+}
+
+// Validate checks the integrity of the tree and then returns an error
+// telling where the first violation is found, if any.
+// The checks cover those of *RBTree.ValidateWithKeys with the starts of
+// intervals as keys, as well as the maximum end of intervals kept in every
+// node for its subtree.
+func (ivt *IntervalTree) Validate() error {
+	rbt := &ivt.rbt
+
+	if err := rbt.ValidateWithKeys(func(rbtn *RBTreeNode) interface{} {
+		return intervalTreeNodeOf(rbtn).start
+	}); err != nil {
+		return err
+	}
+
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		x := it.Node()
+		node := intervalTreeNodeOf(x)
+		maxEnd := node.end
+
+		for _, y := range [...]*RBTreeNode{x.leftChild, x.rightChild} {
+			if y != nil && intervalTreeNodeOf(y).maxEnd > maxEnd {
+				maxEnd = intervalTreeNodeOf(y).maxEnd
+			}
+		}
+
+		if node.maxEnd != maxEnd {
+			return fmt.Errorf("intrusive: interval tree node [%d, %d): max end is %d, want %d", node.start, node.end, node.maxEnd, maxEnd)
+		}
+	}
+
+	return nil
+}
+
+func (ivt *IntervalTree) findFirstOverlap(x *RBTreeNode, start int64, end int64) *RBTreeNode {
+	rbt := &ivt.rbt
+
+	for !x.isNull(rbt) {
+		node := intervalTreeNodeOf(x)
+
+		if node.maxEnd <= start {
+			break
+		}
+
+		// If any interval in the left subtree ends after the start, either
+		// the first overlap is in the left subtree or no overlap exists at all.
+		if y := x.leftChild; !y.isNull(rbt) && intervalTreeNodeOf(y).maxEnd > start {
+			x = y
+			continue
+		}
+
+		if node.start >= end {
+			break
+		}
+
+		if node.end > start {
+			return x
+		}
+
+		x = x.rightChild
+	}
+
+	return &rbt.header
+}
+
+func (ivt *IntervalTree) findNextOverlap(x *RBTreeNode, start int64, end int64) *RBTreeNode {
+	rbt := &ivt.rbt
+
+	if y := ivt.findFirstOverlap(x.rightChild, start, end); !y.isNull(rbt) {
+		return y
+	}
+
+	for {
+		y := x.parent
+
+		if y.isNull(rbt) {
+			return y
+		}
+
+		if x == y.leftChild {
+			node := intervalTreeNodeOf(y)
+
+			if node.start >= end {
+				return &rbt.header
+			}
+
+			if node.end > start {
+				return y
+			}
+
+			if z := ivt.findFirstOverlap(y.rightChild, start, end); !z.isNull(rbt) {
+				return z
+			}
+		}
+
+		x = y
+	}
+}
+
+// IntervalTreeNode represents a node in an interval tree.
+type IntervalTreeNode struct {
+	rbTreeNode RBTreeNode
+	start      int64
+	end        int64
+	maxEnd     int64
+}
+
+// GetStart returns the start of the interval of the node.
+func (ivtn *IntervalTreeNode) GetStart() int64 {
+	return ivtn.start
+}
+
+// GetEnd returns the end of the interval of the node.
+func (ivtn *IntervalTreeNode) GetEnd() int64 {
+	return ivtn.end
+}
+
+// GetContainer returns a pointer to the container which contains
+// the IntervalTreeNode field about the node at the given offset.
+func (ivtn *IntervalTreeNode) GetContainer(offset uintptr) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(unsafe.Pointer(ivtn)) - offset)
+}
+
+// IsReset indicates whether the node is reset (with a zero value).
+// As a node is reset once removed from a tree, it also indicates whether
+// the node is not in any tree.
+func (ivtn *IntervalTreeNode) IsReset() bool {
+	return ivtn.rbTreeNode.IsReset()
+}
+
+// IntervalTreeIterator represents an iterator over all nodes whose
+// intervals overlap an interval in an interval tree.
+type IntervalTreeIterator struct {
+	ivt            *IntervalTree
+	start, end     int64
+	node, nextNode *RBTreeNode
+}
+
+// Init initializes the iterator and then returns the iterator.
+func (ivti *IntervalTreeIterator) Init(ivt *IntervalTree, start int64, end int64) *IntervalTreeIterator {
+	ivti.ivt = ivt
+	ivti.start = start
+	ivti.end = end
+	ivti.node = ivt.findFirstOverlap(ivt.rbt.root(), start, end)
+	ivti.nextNode = ivti.getNextNode()
+	return ivti
+}
+
+// IsAtEnd indicates whether the iteration has no more nodes.
+func (ivti *IntervalTreeIterator) IsAtEnd() bool {
+	return ivti.node.isNull(&ivti.ivt.rbt)
+}
+
+// Node returns the current node in the iteration.
+// It's safe to remove the current node from the tree for the next
+// node to advance to is pre-cached. Unlike removing, erasing the
+// current node is unsafe, since the iteration goes on through the
+// parent links of nodes, which may lead back to the erased node.
+func (ivti *IntervalTreeIterator) Node() *IntervalTreeNode {
+	return intervalTreeNodeOf(ivti.node)
+}
+
+// Advance advances the iterator to the next node.
+func (ivti *IntervalTreeIterator) Advance() {
+	ivti.node = ivti.nextNode
+	ivti.nextNode = ivti.getNextNode()
+}
+
+func (ivti *IntervalTreeIterator) getNextNode() *RBTreeNode {
+	if ivti.IsAtEnd() {
+		return ivti.node
+	}
+
+	return ivti.ivt.findNextOverlap(ivti.node, ivti.start, ivti.end)
+}
+
+func orderIntervalTreeNode(rbtn1 *RBTreeNode, rbtn2 *RBTreeNode) bool {
+	return intervalTreeNodeOf(rbtn1).start <= intervalTreeNodeOf(rbtn2).start
+}
+
+func compareIntervalTreeNode(rbtn *RBTreeNode, key interface{}) int64 {
+	start := intervalTreeNodeOf(rbtn).start
+
+	switch start2 := key.(int64); {
+	case start < start2:
+		return -1
+	case start > start2:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func augmentIntervalTreeNode(rbtn *RBTreeNode, leftChild *RBTreeNode, rightChild *RBTreeNode) bool {
+	node := intervalTreeNodeOf(rbtn)
+	maxEnd := node.end
+
+	if leftChild != nil {
+		if leftMaxEnd := intervalTreeNodeOf(leftChild).maxEnd; leftMaxEnd > maxEnd {
+			maxEnd = leftMaxEnd
+		}
+	}
+
+	if rightChild != nil {
+		if rightMaxEnd := intervalTreeNodeOf(rightChild).maxEnd; rightMaxEnd > maxEnd {
+			maxEnd = rightMaxEnd
+		}
+	}
+
+	if maxEnd == node.maxEnd {
+		return false
+	}
+
+	node.maxEnd = maxEnd
+	return true
+}
+
+func intervalTreeNodeOf(rbtn *RBTreeNode) *IntervalTreeNode {
+	return (*IntervalTreeNode)(rbtn.GetContainer(unsafe.Offsetof(IntervalTreeNode{}.rbTreeNode)))
+}
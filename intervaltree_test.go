@@ -0,0 +1,247 @@
+package intrusive_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalTreeFindFirstOverlap(t *testing.T) {
+	for i, tt := range []struct {
+		In  [2]int64
+		Out string
+	}{
+		{
+			In:  [2]int64{0, 1},
+			Out: "",
+		},
+		{
+			In:  [2]int64{0, 2},
+			Out: "1-5",
+		},
+		{
+			In:  [2]int64{5, 6},
+			Out: "2-8",
+		},
+		{
+			In:  [2]int64{8, 10},
+			Out: "7-9",
+		},
+		{
+			In:  [2]int64{15, 20},
+			Out: "",
+		},
+		{
+			In:  [2]int64{13, 14},
+			Out: "12-15",
+		},
+	} {
+		ivt := new(intrusive.IntervalTree).Init()
+		_, ok := ivt.FindFirstOverlap(tt.In[0], tt.In[1])
+		assert.False(t, ok)
+		for _, vv := range [][2]int64{{7, 9}, {1, 5}, {12, 15}, {2, 8}, {10, 11}} {
+			r := &recordOfIntervalTree{Start: vv[0], End: vv[1]}
+			ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+		}
+		var out string
+		if ivtn, ok := ivt.FindFirstOverlap(tt.In[0], tt.In[1]); ok {
+			r := (*recordOfIntervalTree)(ivtn.GetContainer(unsafe.Offsetof(recordOfIntervalTree{}.IntervalTreeNode)))
+			out = fmt.Sprintf("%v-%v", r.Start, r.End)
+			assert.Equal(t, r.Start, ivtn.GetStart())
+			assert.Equal(t, r.End, ivtn.GetEnd())
+		}
+		assert.Equal(t, tt.Out, out, "case %d", i)
+	}
+}
+
+func TestIntervalTreeForeachOverlap(t *testing.T) {
+	for i, tt := range []struct {
+		In  [2]int64
+		Out string
+	}{
+		{
+			In:  [2]int64{0, 100},
+			Out: "1-5,2-8,7-9,10-11,12-15",
+		},
+		{
+			In:  [2]int64{4, 8},
+			Out: "1-5,2-8,7-9",
+		},
+		{
+			In:  [2]int64{9, 10},
+			Out: "",
+		},
+		{
+			In:  [2]int64{8, 13},
+			Out: "7-9,10-11,12-15",
+		},
+	} {
+		ivt := new(intrusive.IntervalTree).Init()
+		for _, vv := range [][2]int64{{7, 9}, {1, 5}, {12, 15}, {2, 8}, {10, 11}} {
+			r := &recordOfIntervalTree{Start: vv[0], End: vv[1]}
+			ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+		}
+		assert.Equal(t, tt.Out, dumpRecordIntervalTree(ivt, ivt.ForeachOverlap(tt.In[0], tt.In[1])), "case %d", i)
+	}
+}
+
+func TestIntervalTreeForeachStab(t *testing.T) {
+	for i, tt := range []struct {
+		In  int64
+		Out string
+	}{
+		{
+			In:  0,
+			Out: "",
+		},
+		{
+			In:  1,
+			Out: "1-5",
+		},
+		{
+			In:  7,
+			Out: "2-8,7-9",
+		},
+		{
+			In:  9,
+			Out: "",
+		},
+		{
+			In:  math.MaxInt64 - 1,
+			Out: "14-9223372036854775807",
+		},
+		{
+			In:  math.MaxInt64,
+			Out: "",
+		},
+	} {
+		ivt := new(intrusive.IntervalTree).Init()
+		for _, vv := range [][2]int64{{7, 9}, {1, 5}, {12, 15}, {2, 8}, {10, 11}, {14, math.MaxInt64}} {
+			r := &recordOfIntervalTree{Start: vv[0], End: vv[1]}
+			ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+		}
+		assert.Equal(t, tt.Out, dumpRecordIntervalTree(ivt, ivt.ForeachStab(tt.In)), "case %d", i)
+	}
+}
+
+func TestIntervalTree(t *testing.T) {
+	ivt := new(intrusive.IntervalTree).Init()
+	assert.True(t, ivt.IsEmpty())
+	var rs [10000]recordOfIntervalTree
+	for i := range rs {
+		r := &rs[i]
+		r.Start = rand.Int63n(100000)
+		r.End = r.Start + 1 + rand.Int63n(100)
+		assert.True(t, r.IntervalTreeNode.IsReset())
+		ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+		assert.False(t, r.IntervalTreeNode.IsReset())
+	}
+	for i := 0; i < len(rs); i += 2 {
+		ivt.RemoveNode(&rs[i].IntervalTreeNode)
+	}
+	assert.Equal(t, len(rs)/2, ivt.NumberOfNodes())
+	assert.NoError(t, ivt.Validate())
+	for n := 0; n < 100; n++ {
+		start := rand.Int63n(100000)
+		end := start + rand.Int63n(1000)
+		m := make(map[*recordOfIntervalTree]struct{})
+		for i := 1; i < len(rs); i += 2 {
+			if r := &rs[i]; r.Start < end && r.End > start {
+				m[r] = struct{}{}
+			}
+		}
+		var lastStart int64 = -1
+		for it := ivt.ForeachOverlap(start, end); !it.IsAtEnd(); it.Advance() {
+			r := (*recordOfIntervalTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfIntervalTree{}.IntervalTreeNode)))
+			_, ok := m[r]
+			assert.True(t, ok)
+			delete(m, r)
+			assert.GreaterOrEqual(t, r.Start, lastStart)
+			lastStart = r.Start
+			if n%2 == 0 {
+				ivt.RemoveNode(it.Node())
+				ivt.InsertNode(it.Node(), r.Start, r.End)
+			}
+		}
+		assert.Len(t, m, 0)
+	}
+	for it := ivt.ForeachOverlap(0, 200000); !it.IsAtEnd(); it.Advance() {
+		ivt.RemoveNode(it.Node())
+	}
+	assert.True(t, ivt.IsEmpty())
+}
+
+func TestIntervalTreeValidate(t *testing.T) {
+	for i, tt := range []struct {
+		In  func([]recordOfIntervalTree)
+		Err string
+	}{
+		{
+			In:  func(rs []recordOfIntervalTree) {},
+			Err: "",
+		},
+		{
+			In: func(rs []recordOfIntervalTree) {
+				*maxEndOfIntervalTreeNode(&rs[1].IntervalTreeNode) = 100
+			},
+			Err: "intrusive: interval tree node [1, 5): max end is 100, want 8",
+		},
+		{
+			In: func(rs []recordOfIntervalTree) {
+				*maxEndOfIntervalTreeNode(&rs[2].IntervalTreeNode) = 0
+			},
+			Err: "intrusive: interval tree node [7, 9): max end is 15, want 9",
+		},
+	} {
+		ivt := new(intrusive.IntervalTree).Init()
+		assert.NoError(t, ivt.Validate(), "case %d", i)
+		rs := []recordOfIntervalTree{{Start: 7, End: 9}, {Start: 1, End: 5}, {Start: 12, End: 15}, {Start: 2, End: 8}, {Start: 10, End: 11}}
+		for j := range rs {
+			r := &rs[j]
+			ivt.InsertNode(&r.IntervalTreeNode, r.Start, r.End)
+		}
+		assert.NoError(t, ivt.Validate(), "case %d", i)
+		tt.In(rs)
+		if tt.Err == "" {
+			assert.NoError(t, ivt.Validate(), "case %d", i)
+		} else {
+			assert.EqualError(t, ivt.Validate(), tt.Err, "case %d", i)
+		}
+	}
+}
+
+type recordOfIntervalTree struct {
+	Start, End       int64
+	IntervalTreeNode intrusive.IntervalTreeNode
+}
+
+func dumpRecordIntervalTree(intervalTree *intrusive.IntervalTree, it *intrusive.IntervalTreeIterator) string {
+	var buffer bytes.Buffer
+
+	for ; !it.IsAtEnd(); it.Advance() {
+		record := (*recordOfIntervalTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfIntervalTree{}.IntervalTreeNode)))
+		intervalTree.RemoveNode(it.Node()) // destry the tree
+		fmt.Fprintf(&buffer, "%v-%v,", record.Start, record.End)
+	}
+
+	if n := buffer.Len(); n >= 1 {
+		buffer.Truncate(n - 1)
+		return buffer.String()
+	}
+
+	return ""
+}
+
+// maxEndOfIntervalTreeNode returns the maximum end kept in the given node,
+// which is the last field of IntervalTreeNode, for corrupting interval
+// trees.
+func maxEndOfIntervalTreeNode(intervalTreeNode *intrusive.IntervalTreeNode) *int64 {
+	offset := unsafe.Sizeof(*intervalTreeNode) - unsafe.Sizeof(int64(0))
+	return (*int64)(unsafe.Pointer(uintptr(unsafe.Pointer(intervalTreeNode)) + offset))
+}
@@ -1,28 +1,72 @@
 package intrusive
 
-import "unsafe"
+import (
+	"fmt"
+	"iter"
+	"unsafe"
+)
 
 // List presents a doubly-linked list.
+//
+// The list keeps track of the number of its nodes, as long as nodes
+// join or leave the list through methods of List. The deprecated methods
+// of ListNode and package-level slice functions don't know the list the
+// nodes belong to, so they leave the number of nodes untouched.
 type List struct {
-	nil ListNode
+	nil       ListNode
+	nodeCount int
 }
 
 // Init initializes the list and then returns the list.
 func (l *List) Init() *List {
-	l.nil = ListNode{&l.nil, &l.nil}
+	l.nil = ListNode{prev: &l.nil, next: &l.nil}
+	l.nil.owner.attach(unsafe.Pointer(l), "ListNode")
+	l.nodeCount = 0
 	return l
 }
 
 // AppendNode inserts the given node at the end of the list.
 // The given node must be not null.
 func (l *List) AppendNode(node *ListNode) {
+	node.owner.attach(unsafe.Pointer(l), "ListNode")
 	node.insert(l.Tail(), &l.nil)
+	l.nodeCount++
 }
 
 // PrependNode inserts the given node at the beginning of the list.
 // The given node must be not null.
 func (l *List) PrependNode(node *ListNode) {
+	node.owner.attach(unsafe.Pointer(l), "ListNode")
 	node.insert(&l.nil, l.Head())
+	l.nodeCount++
+}
+
+// InsertNodeBefore inserts the given node before the given other node
+// in the list.
+// Inserting the given node before a null node is legal as if inserting
+// at the end of the list.
+func (l *List) InsertNodeBefore(node *ListNode, other *ListNode) {
+	other.owner.check(unsafe.Pointer(l), "ListNode")
+	node.InsertBefore(other)
+	l.nodeCount++
+}
+
+// InsertNodeAfter inserts the given node after the given other node
+// in the list.
+// Inserting the given node after a null node is legal as if inserting
+// at the beginning of the list.
+func (l *List) InsertNodeAfter(node *ListNode, other *ListNode) {
+	other.owner.check(unsafe.Pointer(l), "ListNode")
+	node.InsertAfter(other)
+	l.nodeCount++
+}
+
+// RemoveNode removes the given node from the list and then resets the node.
+// The given node must be in the list.
+func (l *List) RemoveNode(node *ListNode) {
+	node.owner.check(unsafe.Pointer(l), "ListNode")
+	node.Remove()
+	l.nodeCount--
 }
 
 // AppendNodes removes all nodes of the given other list and then inserts
@@ -32,7 +76,10 @@ func (l *List) AppendNodes(other *List) {
 		return
 	}
 
-	insertListSlice(other.Head(), other.Tail(), l.Tail(), &l.nil)
+	firstNode, lastNode := other.Head(), other.Tail()
+	adoptListSlice(firstNode, lastNode, unsafe.Pointer(l))
+	insertListSlice(firstNode, lastNode, l.Tail(), &l.nil)
+	l.nodeCount += other.nodeCount
 	other.Init()
 }
 
@@ -43,20 +90,82 @@ func (l *List) PrependNodes(other *List) {
 		return
 	}
 
-	insertListSlice(other.Head(), other.Tail(), &l.nil, l.Head())
+	firstNode, lastNode := other.Head(), other.Tail()
+	adoptListSlice(firstNode, lastNode, unsafe.Pointer(l))
+	insertListSlice(firstNode, lastNode, &l.nil, l.Head())
+	l.nodeCount += other.nodeCount
 	other.Init()
 }
 
 // AppendSlice inserts the given slice at the end of the list.
 // The given slice must not contain null node.
+// It counts the nodes of the slice in O(k) time, where k is the number
+// of the nodes, using *List.InsertSliceBefore with a null node
+// instead if the number of the nodes is known.
 func (l *List) AppendSlice(firstNode *ListNode, lastNode *ListNode) {
-	insertListSlice(firstNode, lastNode, l.Tail(), &l.nil)
+	l.InsertSliceBefore(firstNode, lastNode, countListSlice(firstNode, lastNode), &l.nil)
 }
 
 // PrependSlice inserts the given slice at the beginning of the list.
 // The given slice must not contain null node.
+// It counts the nodes of the slice in O(k) time, where k is the number
+// of the nodes, using *List.InsertSliceAfter with a null node
+// instead if the number of the nodes is known.
 func (l *List) PrependSlice(firstNode *ListNode, lastNode *ListNode) {
-	insertListSlice(firstNode, lastNode, &l.nil, l.Head())
+	l.InsertSliceAfter(firstNode, lastNode, countListSlice(firstNode, lastNode), &l.nil)
+}
+
+// InsertSliceBefore inserts the given slice with the given number of
+// nodes before the given node in the list.
+// Inserting the given slice before a null node is legal as if inserting
+// at the end of the list.
+// The given slice must not contain null node.
+func (l *List) InsertSliceBefore(firstNode *ListNode, lastNode *ListNode, nodeCount int, node *ListNode) {
+	node.owner.check(unsafe.Pointer(l), "ListNode")
+	InsertListSliceBefore(firstNode, lastNode, node)
+	l.nodeCount += nodeCount
+}
+
+// InsertSliceAfter inserts the given slice with the given number of
+// nodes after the given node in the list.
+// Inserting the given slice after a null node is legal as if inserting
+// at the beginning of the list.
+// The given slice must not contain null node.
+func (l *List) InsertSliceAfter(firstNode *ListNode, lastNode *ListNode, nodeCount int, node *ListNode) {
+	node.owner.check(unsafe.Pointer(l), "ListNode")
+	InsertListSliceAfter(firstNode, lastNode, node)
+	l.nodeCount += nodeCount
+}
+
+// RemoveSlice removes the given slice with the given number of nodes
+// from the list.
+// The nodes of the slice stay linked to each other, so none of them
+// is reset.
+// The given slice must be in the list.
+func (l *List) RemoveSlice(firstNode *ListNode, lastNode *ListNode, nodeCount int) {
+	firstNode.owner.check(unsafe.Pointer(l), "ListNode")
+	RemoveListSlice(firstNode, lastNode)
+	l.nodeCount -= nodeCount
+}
+
+// Clear removes all nodes from the list in O(n) time, resetting each of
+// the nodes to a zero value.
+// The given callback, if not nil, is called with each node in order,
+// after the node has been reset.
+func (l *List) Clear(onNode func(*ListNode)) {
+	node := l.Head()
+	l.Init()
+
+	for node != &l.nil {
+		nextNode := node.next
+		*node = ListNode{}
+
+		if onNode != nil {
+			onNode(node)
+		}
+
+		node = nextNode
+	}
 }
 
 // Foreach returns an iterator over all nodes in the list in order.
@@ -70,11 +179,95 @@ func (l *List) ForeachReverse() *ListReverseIterator {
 	return new(ListReverseIterator).Init(l)
 }
 
+// All returns an iterator, for use with range-over-func, over all nodes
+// in the list.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+func (l *List) All() iter.Seq[*ListNode] {
+	return func(yield func(*ListNode) bool) {
+		var it ListIterator
+
+		for it.Init(l); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator, for use with range-over-func, over all
+// nodes in the list in reverse order.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+func (l *List) Backward() iter.Seq[*ListNode] {
+	return func(yield func(*ListNode) bool) {
+		var it ListReverseIterator
+
+		for it.Init(l); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
 // IsEmpty indicates whether the list is empty.
 func (l *List) IsEmpty() bool {
 	return l.Tail() == &l.nil
 }
 
+// NumberOfNodes returns the number of nodes in the list.
+func (l *List) NumberOfNodes() int {
+	return l.nodeCount
+}
+
+// Validate checks the integrity of the list and then returns an error
+// telling where the first violation is found, if any.
+// The prev link and the next link of every pair of adjacent nodes must
+// agree with each other, the nodes must form a cycle through the nil
+// of the list, and the number of nodes kept by the list must be exact,
+// which no longer holds once nodes join or leave the list through the
+// deprecated methods of ListNode or package-level slice functions.
+// Nodes are numbered by their positions from the head starting with 1,
+// with 0 standing for the nil.
+func (l *List) Validate() error {
+	x := &l.nil
+
+	// As every link is checked in both directions, a cycle not through
+	// the nil is detected as soon as the walk enters it.
+	for i := 0; ; i++ {
+		y := x.next
+
+		if y == nil {
+			return fmt.Errorf("intrusive: list node #%d: next link is nil", i)
+		}
+
+		j := i + 1
+
+		if y == &l.nil {
+			j = 0
+		}
+
+		if y.prev != x {
+			return fmt.Errorf("intrusive: list node #%d: prev link disagrees with next link of node #%d", j, i)
+		}
+
+		if j == 0 {
+			if l.nodeCount != i {
+				return fmt.Errorf("intrusive: list: number of nodes is %d, want %d", l.nodeCount, i)
+			}
+
+			return nil
+		}
+
+		if debugMode && y.owner.getContainer() != unsafe.Pointer(l) {
+			return fmt.Errorf("intrusive: list node #%d: not owned by the list", j)
+		}
+
+		x = y
+	}
+}
+
 // Tail returns the last node of the list.
 // The last node may be null (using *ListNode.IsNull to test)
 // when the list is empty.
@@ -91,27 +284,43 @@ func (l *List) Head() *ListNode {
 
 // ListNode represents a node in a doubly-linked list.
 type ListNode struct {
+	owner      nodeOwner
 	prev, next *ListNode
 }
 
 // InsertBefore inserts the node before the given other node.
 // Inserting the node before a null node is legal as if inserting
 // at the end of a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.InsertNodeBefore instead.
 func (ln *ListNode) InsertBefore(other *ListNode) {
+	other.owner.checkAttached("ListNode")
+	ln.owner.attach(other.owner.getContainer(), "ListNode")
 	ln.insert(other.prev, other)
 }
 
 // InsertAfter inserts the node after the given other node.
 // Inserting the node after a null node is legal as if inserting
 // at the beginning of a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.InsertNodeAfter instead.
 func (ln *ListNode) InsertAfter(other *ListNode) {
+	other.owner.checkAttached("ListNode")
+	ln.owner.attach(other.owner.getContainer(), "ListNode")
 	ln.insert(other, other.next)
 }
 
-// Remove removes the node from a list.
+// Remove removes the node from a list and then resets the node.
 // The node must be in a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.RemoveNode instead.
 func (ln *ListNode) Remove() {
+	ln.owner.checkAttached("ListNode")
 	ln.prev.setNext(ln.next)
+	*ln = ListNode{}
 }
 
 // GetContainer returns a pointer to the container which contains
@@ -128,6 +337,8 @@ func (ln *ListNode) IsNull(l *List) bool {
 }
 
 // IsReset indicates whether the node is reset (with a zero value).
+// As a node is reset once removed from a list, it also indicates whether
+// the node is not in any list, unless the node is in a removed slice.
 func (ln *ListNode) IsReset() bool {
 	return ln.prev == nil
 }
@@ -207,7 +418,12 @@ func (lri *ListReverseIterator) Advance() {
 // Inserting the given slice before a null node is legal as if inserting
 // at the end of a list.
 // The given node must be in a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.InsertSliceBefore instead.
 func InsertListSliceBefore(firstListNode *ListNode, lastListNode *ListNode, listNode *ListNode) {
+	listNode.owner.checkAttached("ListNode")
+	adoptListSlice(firstListNode, lastListNode, listNode.owner.getContainer())
 	insertListSlice(firstListNode, lastListNode, listNode.prev, listNode)
 }
 
@@ -215,13 +431,24 @@ func InsertListSliceBefore(firstListNode *ListNode, lastListNode *ListNode, list
 // Inserting the given slice after a null node is legal as if inserting
 // at the beginning of a list.
 // The given node must be in a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.InsertSliceAfter instead.
 func InsertListSliceAfter(firstListNode *ListNode, lastListNode *ListNode, listNode *ListNode) {
+	listNode.owner.checkAttached("ListNode")
+	adoptListSlice(firstListNode, lastListNode, listNode.owner.getContainer())
 	insertListSlice(firstListNode, lastListNode, listNode, listNode.next)
 }
 
 // RemoveListSlice removes the given slice from a list.
+// The nodes of the slice stay linked to each other, so none of them
+// is reset.
 // The given slice must be in a list.
+//
+// Deprecated: It leaves the number of nodes of the list untouched,
+// use *List.RemoveSlice instead.
 func RemoveListSlice(firstListNode *ListNode, lastListNode *ListNode) {
+	detachListSlice(firstListNode, lastListNode)
 	firstListNode.prev.setNext(lastListNode.next)
 }
 
@@ -252,3 +479,47 @@ func insertListSlice(firstListNode *ListNode, lastListNode *ListNode, firstListN
 	firstListNode.setPrev(firstListNodePrev)
 	lastListNode.setNext(lastListNodeNext)
 }
+
+// adoptListSlice makes the given container own all nodes of the given
+// slice, which matters only in debug mode.
+// Slices may be moved between lists without being removed first, so
+// the nodes may have been owned by other lists.
+func adoptListSlice(firstListNode *ListNode, lastListNode *ListNode, container unsafe.Pointer) {
+	if !debugMode {
+		return
+	}
+
+	for listNode := firstListNode; ; listNode = listNode.next {
+		listNode.owner.detach()
+		listNode.owner.attach(container, "ListNode")
+
+		if listNode == lastListNode {
+			return
+		}
+	}
+}
+
+func countListSlice(firstListNode *ListNode, lastListNode *ListNode) int {
+	n := 1
+
+	for listNode := firstListNode; listNode != lastListNode; listNode = listNode.next {
+		n++
+	}
+
+	return n
+}
+
+func detachListSlice(firstListNode *ListNode, lastListNode *ListNode) {
+	if !debugMode {
+		return
+	}
+
+	for listNode := firstListNode; ; listNode = listNode.next {
+		listNode.owner.checkAttached("ListNode")
+		listNode.owner.detach()
+
+		if listNode == lastListNode {
+			return
+		}
+	}
+}
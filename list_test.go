@@ -138,7 +138,9 @@ func TestListRemoveNode(t *testing.T) {
 		},
 		{
 			In: func(l *intrusive.List) {
-				l.Head().Remove()
+				x := l.Head()
+				x.Remove()
+				assert.True(t, x.IsReset())
 				l.Tail().Remove()
 				l.Head().Next().Remove()
 				l.Tail().Prev().Remove()
@@ -176,6 +178,241 @@ func TestListRemoveNode(t *testing.T) {
 	}
 }
 
+func TestListNumberOfNodes(t *testing.T) {
+	for i, tt := range []struct {
+		In           func(*intrusive.List)
+		Out          string
+		OutIsReverse bool
+		NodeCount    int
+	}{
+		{
+			In:        func(l *intrusive.List) {},
+			Out:       "1,2,3,4,5,6",
+			NodeCount: 6,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l.InsertNodeBefore(&(&recordOfList{Value: 7}).ListNode, l.Head())
+				l.InsertNodeAfter(&(&recordOfList{Value: 8}).ListNode, l.Tail())
+				l.InsertNodeAfter(&(&recordOfList{Value: 9}).ListNode, l.Head())
+				x := l.Head().Next().Next()
+				assert.False(t, x.IsReset())
+				l.RemoveNode(x)
+				assert.True(t, x.IsReset())
+			},
+			Out:       "7,9,2,3,4,5,6,8",
+			NodeCount: 8,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l.RemoveSlice(l.Head().Next(), l.Tail().Prev(), 4)
+			},
+			Out:          "6,1",
+			OutIsReverse: true,
+			NodeCount:    2,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l2 := new(intrusive.List).Init()
+				for i := 6; i < 9; i++ {
+					l2.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+				}
+				assert.Equal(t, 3, l2.NumberOfNodes())
+				x, y := l2.Head(), l2.Tail()
+				l2.RemoveSlice(x, y, 3)
+				assert.Equal(t, 0, l2.NumberOfNodes())
+				assert.True(t, l2.IsEmpty())
+				l.InsertSliceAfter(x, y, 3, l.Head())
+			},
+			Out:       "1,7,8,9,2,3,4,5,6",
+			NodeCount: 9,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l2 := new(intrusive.List).Init()
+				for i := 6; i < 8; i++ {
+					l2.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+				}
+				x, y := l2.Head(), l2.Tail()
+				l2.RemoveSlice(x, y, 2)
+				l.InsertSliceBefore(x, y, 2, l.Tail())
+				l.AppendNodes(l2)
+			},
+			Out:       "1,2,3,4,5,7,8,6",
+			NodeCount: 8,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l2 := new(intrusive.List).Init()
+				for i := 6; i < 8; i++ {
+					l2.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+				}
+				l.PrependNodes(l2)
+				assert.Equal(t, 0, l2.NumberOfNodes())
+				l.AppendNodes(new(intrusive.List).Init())
+			},
+			Out:       "7,8,1,2,3,4,5,6",
+			NodeCount: 8,
+		},
+		{
+			In: func(l *intrusive.List) {
+				l2 := new(intrusive.List).Init()
+				for i := 6; i < 9; i++ {
+					l2.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+				}
+				x, y := l2.Head(), l2.Tail()
+				l2.RemoveSlice(x, y, 3)
+				l.AppendSlice(x, y.Prev())
+				l.PrependSlice(y, y)
+			},
+			Out:       "9,1,2,3,4,5,6,7,8",
+			NodeCount: 9,
+		},
+	} {
+		l := new(intrusive.List).Init()
+		assert.Equal(t, 0, l.NumberOfNodes())
+		for i := 0; i < 6; i++ {
+			l.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+		}
+		tt.In(l)
+		assert.NoError(t, l.Validate(), "case %d", i)
+		assert.Equal(t, tt.NodeCount, l.NumberOfNodes(), "case %d", i)
+		if tt.OutIsReverse {
+			assert.Equal(t, tt.Out, dumpReverseRecordList(l), "case %d", i)
+		} else {
+			assert.Equal(t, tt.Out, dumpRecordList(l), "case %d", i)
+		}
+	}
+}
+
+func TestListClear(t *testing.T) {
+	for i, n := range []int{0, 1, 6} {
+		l := new(intrusive.List).Init()
+		rs := make([]recordOfList, n)
+		for j := range rs {
+			rs[j].Value = j + 1
+			l.AppendNode(&rs[j].ListNode)
+		}
+		var values []string
+		l.Clear(func(ln *intrusive.ListNode) {
+			assert.True(t, ln.IsReset(), "case %d", i)
+			r := (*recordOfList)(ln.GetContainer(unsafe.Offsetof(recordOfList{}.ListNode)))
+			values = append(values, fmt.Sprint(r.Value))
+		})
+		assert.True(t, l.IsEmpty(), "case %d", i)
+		assert.Equal(t, 0, l.NumberOfNodes(), "case %d", i)
+		assert.Len(t, values, n, "case %d", i)
+		for j := range rs {
+			assert.True(t, rs[j].ListNode.IsReset(), "case %d", i)
+			assert.Equal(t, fmt.Sprint(j+1), values[j], "case %d", i)
+		}
+		for j := range rs {
+			l.PrependNode(&rs[j].ListNode)
+		}
+		assert.Equal(t, n, l.NumberOfNodes(), "case %d", i)
+		l.Clear(nil)
+		assert.True(t, l.IsEmpty(), "case %d", i)
+	}
+}
+
+func TestListValidate(t *testing.T) {
+	for i, tt := range []struct {
+		In  func(*intrusive.List)
+		Err string
+	}{
+		{
+			In:  func(l *intrusive.List) {},
+			Err: "",
+		},
+		{
+			In: func(l *intrusive.List) {
+				x := l.Head().Next()
+				linksOfListNode(x)[0] = x
+			},
+			Err: "intrusive: list node #2: prev link disagrees with next link of node #1",
+		},
+		{
+			In: func(l *intrusive.List) {
+				linksOfListNode(l.Tail())[1] = nil
+			},
+			Err: "intrusive: list node #6: next link is nil",
+		},
+		{
+			In: func(l *intrusive.List) {
+				linksOfListNode(l.Tail())[1] = l.Head()
+			},
+			Err: "intrusive: list node #7: prev link disagrees with next link of node #6",
+		},
+		{
+			In: func(l *intrusive.List) {
+				linksOfListNode(l.Head().Prev())[0] = l.Head()
+			},
+			Err: "intrusive: list node #0: prev link disagrees with next link of node #6",
+		},
+		{
+			In: func(l *intrusive.List) {
+				l.Head().Remove()
+			},
+			Err: "intrusive: list: number of nodes is 6, want 5",
+		},
+		{
+			In: func(l *intrusive.List) {
+				x := &(&recordOfList{Value: 7}).ListNode
+				l.InsertSliceAfter(x, x, 2, l.Tail())
+			},
+			Err: "intrusive: list: number of nodes is 8, want 7",
+		},
+	} {
+		l := new(intrusive.List).Init()
+		assert.NoError(t, l.Validate(), "case %d", i)
+		for i := 0; i < 6; i++ {
+			l.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+		}
+		tt.In(l)
+		if tt.Err == "" {
+			assert.NoError(t, l.Validate(), "case %d", i)
+		} else {
+			assert.EqualError(t, l.Validate(), tt.Err, "case %d", i)
+		}
+	}
+}
+
+func TestListAllBackward(t *testing.T) {
+	l := new(intrusive.List).Init()
+	for i := 0; i < 6; i++ {
+		l.AppendNode(&(&recordOfList{Value: i + 1}).ListNode)
+	}
+	valueOf := func(ln *intrusive.ListNode) int {
+		return (*recordOfList)(ln.GetContainer(unsafe.Offsetof(recordOfList{}.ListNode))).Value
+	}
+	var vs []int
+	for ln := range l.All() {
+		vs = append(vs, valueOf(ln))
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, vs)
+	vs = nil
+	for ln := range l.Backward() {
+		if vs = append(vs, valueOf(ln)); len(vs) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []int{6, 5, 4}, vs)
+	vs = nil
+	for ln := range l.All() {
+		if v := valueOf(ln); v%2 == 0 {
+			l.RemoveNode(ln)
+		} else {
+			vs = append(vs, v)
+		}
+	}
+	assert.Equal(t, []int{1, 3, 5}, vs)
+	assert.NoError(t, l.Validate())
+	for ln := range l.Backward() {
+		l.RemoveNode(ln)
+	}
+	assert.True(t, l.IsEmpty())
+}
+
 type recordOfList struct {
 	Value    int
 	ListNode intrusive.ListNode
@@ -214,3 +451,10 @@ func dumpReverseRecordList(list *intrusive.List) string {
 
 	return ""
 }
+
+// linksOfListNode returns the prev link and the next link of the given node,
+// which are the last fields of ListNode, for corrupting lists.
+func linksOfListNode(listNode *intrusive.ListNode) *[2]*intrusive.ListNode {
+	offset := unsafe.Sizeof(*listNode) - 2*unsafe.Sizeof(listNode)
+	return (*[2]*intrusive.ListNode)(unsafe.Pointer(uintptr(unsafe.Pointer(listNode)) + offset))
+}
@@ -0,0 +1,27 @@
+//go:build !intrusive_debug
+// +build !intrusive_debug
+
+package intrusive
+
+import "unsafe"
+
+// debugMode indicates whether the package is built with the build tag
+// intrusive_debug, under which nodes keep track of the containers owning
+// them, and misuses of nodes panic instead of corrupting containers.
+const debugMode = false
+
+// nodeOwner is zero-sized and does nothing without the build tag
+// intrusive_debug, so nodes stay at their minimal sizes.
+type nodeOwner struct{}
+
+func (*nodeOwner) attach(unsafe.Pointer, string) {}
+
+func (*nodeOwner) detach() {}
+
+func (*nodeOwner) check(unsafe.Pointer, string) {}
+
+func (*nodeOwner) checkAttached(string) {}
+
+func (*nodeOwner) getContainer() unsafe.Pointer {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !intrusive_debug
+// +build !intrusive_debug
+
+package intrusive_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeSizes(t *testing.T) {
+	const pointerSize = unsafe.Sizeof(uintptr(0))
+	assert.Equal(t, 2*pointerSize, unsafe.Sizeof(intrusive.ListNode{}))
+	assert.Equal(t, unsafe.Sizeof(int(0)), unsafe.Sizeof(intrusive.HeapNode{}))
+	assert.Equal(t, pointerSize+unsafe.Sizeof(uint64(0)), unsafe.Sizeof(intrusive.HashMapNode{}))
+	assert.Equal(t, 3*pointerSize+2*unsafe.Sizeof(int32(0)), unsafe.Sizeof(intrusive.RBTreeNode{}))
+}
@@ -1,47 +1,199 @@
 package intrusive
 
-import "unsafe"
+import (
+	"fmt"
+	"iter"
+	"unsafe"
+)
 
 // RBTree presents a red-black tree.
+// It caches the leftmost node and the rightmost node, so the node with
+// the minimum key and the node with the maximum key are available in O(1)
+// time.
 type RBTree struct {
-	nodeOrderer  RBTreeNodeOrderer
-	nodeComparer RBTreeNodeComparer
-	nil          RBTreeNode
+	nodeOrderer   RBTreeNodeOrderer
+	nodeComparer  RBTreeNodeComparer
+	header        RBTreeNode
+	leftmostNode  *RBTreeNode
+	rightmostNode *RBTreeNode
+	nodeAugmenter RBTreeNodeAugmenter
+	nodeCount     int
+	flags         rbTreeFlags
 }
 
 // Init initializes the tree and then returns the tree.
 func (rbt *RBTree) Init(nodeOrderer RBTreeNodeOrderer, nodeComparer RBTreeNodeComparer) *RBTree {
 	rbt.nodeOrderer = nodeOrderer
 	rbt.nodeComparer = nodeComparer
-	rbt.nil.color = rbTreeNodeBlack
-	rbt.setRoot(&rbt.nil)
+	rbt.header.color = rbTreeNodeBlack
+	rbt.setRoot(nil)
+	rbt.leftmostNode = nil
+	rbt.rightmostNode = nil
+	rbt.nodeAugmenter = nil
+	rbt.nodeCount = 0
+	rbt.flags = 0
+	return rbt
+}
+
+// SetNodeAugmenter makes the tree keep augmented values (summaries of
+// subtrees) of nodes up to date with the given augmenter, and then returns
+// the tree.
+// The augmenter is called to recompute the augmented value of every node
+// whose subtree changes, due to insertions, removals or rotations.
+// It must be called while the tree is empty, otherwise it panics.
+func (rbt *RBTree) SetNodeAugmenter(nodeAugmenter RBTreeNodeAugmenter) *RBTree {
+	if !rbt.IsEmpty() {
+		panic("intrusive: node augmenter set for non-empty RBTree")
+	}
+
+	rbt.nodeAugmenter = nodeAugmenter
+	return rbt
+}
+
+// EnableOrderStatistics makes the tree keep the size of every subtree,
+// enabling the order statistic operations GetNodeByRank, GetRank and
+// CountRange in O(log n) time, and then returns the tree.
+// Subtree sizes are 32-bit, so the tree must hold less than 2^31 nodes.
+// It must be called while the tree is empty, otherwise it panics.
+func (rbt *RBTree) EnableOrderStatistics() *RBTree {
+	if !rbt.IsEmpty() {
+		panic("intrusive: order statistics enabled for non-empty RBTree")
+	}
+
+	rbt.flags |= rbTreeOrderStatistics
+	return rbt
+}
+
+// EnableStableInsertion makes InsertNode insert a node after, instead of
+// before, the nodes with keys equal to the key of the node, so that
+// nodes with equal keys stay in insertion order (first in, first out),
+// and then returns the tree.
+// InsertNodeBefore and InsertNodeAfter keep the order as well, for they
+// fall back to InsertNode unless the node goes right where InsertNode
+// would put it.
+// It must be called while the tree is empty, otherwise it panics.
+func (rbt *RBTree) EnableStableInsertion() *RBTree {
+	if !rbt.IsEmpty() {
+		panic("intrusive: stable insertion enabled for non-empty RBTree")
+	}
+
+	rbt.flags |= rbTreeStableInsertion
 	return rbt
 }
 
 // InsertNode inserts the given node to the tree.
 func (rbt *RBTree) InsertNode(x *RBTreeNode) {
-	y := &rbt.nil
+	y := &rbt.header
 	z, f := y.leftChild /* rbt.root() */, (*RBTreeNode).setLeftChild /* rbt.setRoot() */
 
 	for !z.isNull(rbt) {
 		y = z
 
-		if rbt.nodeOrderer(x, y) {
+		if rbt.nodeGoesBefore(x, y) {
 			z, f = y.leftChild, (*RBTreeNode).setLeftChild
 		} else {
 			z, f = y.rightChild, (*RBTreeNode).setRightChild
 		}
 	}
 
-	x.leftChild = &rbt.nil
-	x.rightChild = &rbt.nil
-	x.color = rbTreeNodeRed
-	f(y, x)
-	rbt.fixAfterNodeInsertion(x)
+	rbt.insertNode(x, y, f)
+}
+
+// InsertNodeUnique inserts the given node with the given key to the tree
+// unless a node with an identical key exists, in a single descent from the
+// root, and then returns the node with the given key in the tree and
+// a boolean indicating whether the given node has been inserted.
+// The given key must be the key of the given node.
+func (rbt *RBTree) InsertNodeUnique(x *RBTreeNode, key interface{}) (*RBTreeNode, bool) {
+	y, f, ok := rbt.findNodeOrSlot(key)
+
+	if ok {
+		return y, false
+	}
+
+	rbt.insertNode(x, y, f)
+	return x, true
+}
+
+// FindOrInsertNode finds a node with the given key in the tree, or
+// otherwise inserts the node created by the given factory with the given
+// key to the tree, in a single descent from the root, and then returns
+// the node and a boolean indicating whether the node has been inserted.
+func (rbt *RBTree) FindOrInsertNode(key interface{}, nodeFactory func() *RBTreeNode) (*RBTreeNode, bool) {
+	y, f, ok := rbt.findNodeOrSlot(key)
+
+	if ok {
+		return y, false
+	}
+
+	x := nodeFactory()
+	rbt.insertNode(x, y, f)
+	return x, true
 }
 
-// RemoveNode removes the given node from the tree.
+// InsertNodeBefore inserts the given node to the tree right before the
+// given hint node, skipping the descent from the root, if the key of the
+// given node fits between the keys of the hint node and its previous node.
+// Otherwise it falls back to InsertNode.
+// The given hint node must be in the tree.
+func (rbt *RBTree) InsertNodeBefore(x *RBTreeNode, hint *RBTreeNode) {
+	hint.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+
+	if rbt.nodeGoesBefore(x, hint) {
+		prev, ok := hint.GetPrev(rbt)
+
+		if !ok || !rbt.nodeGoesBefore(x, prev) {
+			if hint.leftChild == nil {
+				rbt.insertNode(x, hint, (*RBTreeNode).setLeftChild)
+			} else {
+				rbt.insertNode(x, prev, (*RBTreeNode).setRightChild)
+			}
+
+			return
+		}
+	}
+
+	rbt.InsertNode(x)
+}
+
+// InsertNodeAfter inserts the given node to the tree right after the
+// given hint node, skipping the descent from the root, if the key of the
+// given node fits between the keys of the hint node and its next node.
+// Otherwise it falls back to InsertNode.
+// The given hint node must be in the tree.
+func (rbt *RBTree) InsertNodeAfter(x *RBTreeNode, hint *RBTreeNode) {
+	hint.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+
+	if !rbt.nodeGoesBefore(x, hint) {
+		next, ok := hint.GetNext(rbt)
+
+		if !ok || rbt.nodeGoesBefore(x, next) {
+			if hint.rightChild == nil {
+				rbt.insertNode(x, hint, (*RBTreeNode).setRightChild)
+			} else {
+				rbt.insertNode(x, next, (*RBTreeNode).setLeftChild)
+			}
+
+			return
+		}
+	}
+
+	rbt.InsertNode(x)
+}
+
+// RemoveNode removes the given node from the tree and then resets
+// the node.
 func (rbt *RBTree) RemoveNode(x *RBTreeNode) {
+	x.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+
+	if x == rbt.leftmostNode {
+		rbt.leftmostNode, _ = x.getNext(rbt)
+	}
+
+	if x == rbt.rightmostNode {
+		rbt.rightmostNode, _ = x.getPrev(rbt)
+	}
+
 	var y, z *RBTreeNode
 
 	if x.leftChild.isNull(rbt) {
@@ -62,18 +214,138 @@ func (rbt *RBTree) RemoveNode(x *RBTreeNode) {
 		}
 	}
 
+	v := y.parent
 	y.replace(z)
 	isBroken := y.color == rbTreeNodeBlack
 
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		rbt.adjustSizes(v, -1)
+	}
+
 	if x != y {
 		y.setLeftChild(x.leftChild)
 		y.setRightChild(x.rightChild)
 		y.color = x.color
+		y.size = x.size
 		x.replace(y)
+
+		if v == x {
+			v = y
+		}
+	}
+
+	if rbt.nodeAugmenter != nil {
+		if x == y {
+			rbt.propagateAugmentation(v, &rbt.header)
+		} else {
+			// y has not inherited the augmented value of x, so recompute
+			// the value of y unconditionally.
+			rbt.propagateAugmentation(v, y)
+			rbt.augmentNode(y)
+			rbt.propagateAugmentation(y.parent, &rbt.header)
+		}
 	}
 
 	if isBroken {
-		rbt.fixAfterNodeRemoval(z)
+		rbt.fixAfterNodeRemoval(z, v)
+	}
+
+	*x = RBTreeNode{}
+	rbt.nodeCount--
+}
+
+// UpdateNode repositions the given node in the tree after the key of
+// the node has changed, and then returns a boolean indicating whether
+// the node has been moved.
+// If the key of the node still fits between the keys of its previous node
+// and its next node, the node stays in place, otherwise it is removed
+// from the tree and inserted to the tree again.
+func (rbt *RBTree) UpdateNode(x *RBTreeNode) bool {
+	prev, prevOk := x.GetPrev(rbt)
+	next, nextOk := x.GetNext(rbt)
+
+	if (!prevOk || rbt.nodeOrderer(prev, x)) && (!nextOk || rbt.nodeOrderer(x, next)) {
+		if rbt.nodeAugmenter != nil {
+			rbt.propagateAugmentation(x, &rbt.header)
+		}
+
+		return false
+	}
+
+	rbt.RemoveNode(x)
+	rbt.InsertNode(x)
+	return true
+}
+
+// ReplaceNode puts the given new node in the exact position and color of
+// the given old node in the tree, without rebalancing, and then resets
+// the old node.
+// The key of the new node must fit in the position of the old node.
+func (rbt *RBTree) ReplaceNode(oldNode *RBTreeNode, newNode *RBTreeNode) {
+	oldNode.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	newNode.owner.attach(unsafe.Pointer(rbt), "RBTreeNode")
+	newNode.setLeftChild(oldNode.leftChild)
+	newNode.setRightChild(oldNode.rightChild)
+	newNode.color = oldNode.color
+	newNode.size = oldNode.size
+	oldNode.replace(newNode)
+
+	if oldNode == rbt.leftmostNode {
+		rbt.leftmostNode = newNode
+	}
+
+	if oldNode == rbt.rightmostNode {
+		rbt.rightmostNode = newNode
+	}
+
+	if rbt.nodeAugmenter != nil {
+		// newNode has not inherited the augmented value of oldNode,
+		// so recompute the value of newNode unconditionally.
+		rbt.augmentNode(newNode)
+		rbt.propagateAugmentation(newNode.parent, &rbt.header)
+	}
+
+	*oldNode = RBTreeNode{}
+}
+
+// Clear removes all nodes from the tree in O(n) time, visiting the nodes
+// in post-order without rebalancing, and resetting each of the nodes to
+// a zero value.
+// The given callback, if not nil, is called with each node in post-order,
+// after the node has been reset.
+func (rbt *RBTree) Clear(onNode func(*RBTreeNode)) {
+	x := rbt.root()
+	rbt.reset()
+
+	if x == nil {
+		return
+	}
+
+	x = x.getFirstPostOrderNode()
+
+	for {
+		y := x.parent
+		z := y.rightChild
+
+		if x == z {
+			z = nil
+		}
+
+		*x = RBTreeNode{}
+
+		if onNode != nil {
+			onNode(x)
+		}
+
+		if y == &rbt.header {
+			return
+		}
+
+		if z == nil {
+			x = y
+		} else {
+			x = z.getFirstPostOrderNode()
+		}
 	}
 }
 
@@ -81,18 +353,93 @@ func (rbt *RBTree) RemoveNode(x *RBTreeNode) {
 // then returns the node.
 // If no node with an identical key exists, it returns false.
 func (rbt *RBTree) FindNode(key interface{}) (*RBTreeNode, bool) {
+	return findRBTreeNode(rbt, key, rbt.nodeComparer)
+}
+
+// FindEqualRange finds the first node and the last node with keys
+// equal to the given key in the tree and then returns the nodes.
+// If no node with an identical key exists, it returns false.
+func (rbt *RBTree) FindEqualRange(key interface{}) (*RBTreeNode, *RBTreeNode, bool) {
+	firstNode := rbt.findFirstNode(key, true)
+
+	if firstNode.isNull(rbt) || rbt.nodeComparer(firstNode, key) != 0 {
+		return nil, nil, false
+	}
+
+	return firstNode, rbt.findLastNode(key, true), true
+}
+
+// CountEqual returns the number of nodes with keys equal to the given key
+// in the tree.
+// It takes O(log n) time if the order statistics of the tree are enabled,
+// or O(log n + k) time otherwise.
+func (rbt *RBTree) CountEqual(key interface{}) int {
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		return rbt.rankFirstNode(key, false) - rbt.rankFirstNode(key, true)
+	}
+
+	n := 0
+
+	for it := rbt.ForeachRange(key, key, true, true); !it.IsAtEnd(); it.Advance() {
+		n++
+	}
+
+	return n
+}
+
+// FindLowerBound finds the first node with a key not less than the
+// given key in the tree and then returns the node.
+// If no such node exists, it returns false.
+func (rbt *RBTree) FindLowerBound(key interface{}) (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.findFirstNode(key, true))
+}
+
+// FindUpperBound finds the first node with a key greater than the
+// given key in the tree and then returns the node.
+// If no such node exists, it returns false.
+func (rbt *RBTree) FindUpperBound(key interface{}) (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.findFirstNode(key, false))
+}
+
+// FindFloor finds the last node with a key not greater than the
+// given key in the tree and then returns the node.
+// If no such node exists, it returns false.
+func (rbt *RBTree) FindFloor(key interface{}) (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.findLastNode(key, true))
+}
+
+// FindCeiling finds the first node with a key not less than the
+// given key in the tree and then returns the node, as the counterpart
+// of FindFloor (being equivalent to FindLowerBound).
+// If no such node exists, it returns false.
+func (rbt *RBTree) FindCeiling(key interface{}) (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.findFirstNode(key, true))
+}
+
+// GetNodeByRank returns the node with the given zero-based rank in the
+// tree, which is the number of nodes before the node in order.
+// If the given rank is out of range, it returns false.
+// The order statistics of the tree must be enabled, otherwise it panics.
+func (rbt *RBTree) GetNodeByRank(rank int) (*RBTreeNode, bool) {
+	rbt.checkOrderStatistics()
+
+	if rank < 0 {
+		return nil, false
+	}
+
 	x := rbt.root()
 
 	for !x.isNull(rbt) {
-		d := -rbt.nodeComparer(x, key)
+		n := x.leftChild.getSize()
 
-		if d == 0 {
+		if rank == n {
 			return x, true
 		}
 
-		if d < 0 {
+		if rank < n {
 			x = x.leftChild
 		} else {
+			rank -= n + 1
 			x = x.rightChild
 		}
 	}
@@ -100,6 +447,39 @@ func (rbt *RBTree) FindNode(key interface{}) (*RBTreeNode, bool) {
 	return nil, false
 }
 
+// GetRank returns the zero-based rank of the given node in the tree,
+// which is the number of nodes before the node in order.
+// The order statistics of the tree must be enabled, otherwise it panics.
+func (rbt *RBTree) GetRank(x *RBTreeNode) int {
+	rbt.checkOrderStatistics()
+	x.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	rank := x.leftChild.getSize()
+
+	for y := x.parent; !y.isNull(rbt); x, y = y, y.parent {
+		if x == y.rightChild {
+			rank += y.leftChild.getSize() + 1
+		}
+	}
+
+	return rank
+}
+
+// CountRange returns the number of nodes with keys within the given range
+// in the tree.
+// The range is bounded by the given minimum key and maximum key,
+// each of which is included or excluded as the given flags indicate.
+// The order statistics of the tree must be enabled, otherwise it panics.
+func (rbt *RBTree) CountRange(minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool) int {
+	rbt.checkOrderStatistics()
+	n := rbt.rankFirstNode(maxKey, !maxKeyIsInclusive) - rbt.rankFirstNode(minKey, minKeyIsInclusive)
+
+	if n < 0 {
+		return 0
+	}
+
+	return n
+}
+
 // Foreach returns an iterator over all nodes in the tree in order.
 func (rbt *RBTree) Foreach() *RBTreeIterator {
 	return new(RBTreeIterator).Init(rbt)
@@ -111,6 +491,241 @@ func (rbt *RBTree) ForeachReverse() *RBTreeReverseIterator {
 	return new(RBTreeReverseIterator).Init(rbt)
 }
 
+// ForeachRange returns an iterator over nodes with keys within the
+// given range in the tree in order.
+// The range is bounded by the given minimum key and maximum key,
+// each of which is included or excluded as the given flags indicate.
+func (rbt *RBTree) ForeachRange(minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool) *RBTreeRangeIterator {
+	return new(RBTreeRangeIterator).Init(rbt, minKey, maxKey, minKeyIsInclusive, maxKeyIsInclusive)
+}
+
+// ForeachRangeReverse returns an iterator over nodes with keys within
+// the given range in the tree in reverse order.
+// The range is bounded by the given minimum key and maximum key,
+// each of which is included or excluded as the given flags indicate.
+func (rbt *RBTree) ForeachRangeReverse(minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool) *RBTreeRangeReverseIterator {
+	return new(RBTreeRangeReverseIterator).Init(rbt, minKey, maxKey, minKeyIsInclusive, maxKeyIsInclusive)
+}
+
+// All returns an iterator, for use with range-over-func, over all nodes
+// in the tree in order.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+func (rbt *RBTree) All() iter.Seq[*RBTreeNode] {
+	return func(yield func(*RBTreeNode) bool) {
+		var it RBTreeIterator
+
+		for it.Init(rbt); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator, for use with range-over-func, over all
+// nodes in the tree in reverse order.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+func (rbt *RBTree) Backward() iter.Seq[*RBTreeNode] {
+	return func(yield func(*RBTreeNode) bool) {
+		var it RBTreeReverseIterator
+
+		for it.Init(rbt); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
+// Range returns an iterator, for use with range-over-func, over nodes
+// with keys within the half-open range [minKey, maxKey) in the tree in
+// order.
+// It's safe to remove the yielded node during the iteration, for the next
+// node to yield is pre-cached.
+// Ranges with other bounds are available with ForeachRange.
+func (rbt *RBTree) Range(minKey interface{}, maxKey interface{}) iter.Seq[*RBTreeNode] {
+	return func(yield func(*RBTreeNode) bool) {
+		var it RBTreeRangeIterator
+
+		for it.Init(rbt, minKey, maxKey, true, false); !it.IsAtEnd(); it.Advance() {
+			if !yield(it.Node()) {
+				return
+			}
+		}
+	}
+}
+
+// BuildFromSorted inserts the given nodes, which must be sorted in order,
+// to the tree in O(n) time, building a balanced tree bottom-up instead of
+// inserting the nodes one by one.
+// The tree must be empty, otherwise it panics.
+func (rbt *RBTree) BuildFromSorted(nodes []*RBTreeNode) {
+	if !rbt.IsEmpty() {
+		panic("intrusive: sorted nodes built into non-empty RBTree")
+	}
+
+	// All levels above the given depth are full, and the nodes at that
+	// depth, if any, are all leaves and colored red.
+	redNodeDepth := 0
+
+	for (2<<redNodeDepth)-1 <= len(nodes) {
+		redNodeDepth++
+	}
+
+	rbt.setRoot(rbt.build(nodes, 0, redNodeDepth))
+	rbt.resetExtremeNodes()
+	rbt.nodeCount = len(nodes)
+}
+
+// SplitAt moves the nodes with keys not less than the given key from the
+// tree to the given other tree, keeping the nodes in place.
+// It takes O(log n) time if the order statistics of the tree are enabled,
+// or O(log n + min(k, n-k)) time otherwise, where k is the number of the
+// moved nodes, for the nodes on the smaller side of the split are counted.
+// The given other tree must be empty and initialized in the same way as
+// the tree.
+func (rbt *RBTree) SplitAt(key interface{}, other *RBTree) {
+	nodeCount := rbt.nodeCount
+	rbt.splitAt(key, true, other)
+
+	if rbt.flags&rbTreeOrderStatistics == 0 {
+		rbt.countNodesAfterSplit(nodeCount, other)
+	}
+}
+
+// Join moves all nodes of the given other tree to the tree, keeping the
+// nodes in place, in O(log n) time.
+// The keys of nodes in the given other tree must be either all not less
+// than or all not greater than the keys of nodes in the tree, and the
+// given other tree must be initialized in the same way as the tree.
+func (rbt *RBTree) Join(other *RBTree) {
+	if other.IsEmpty() {
+		return
+	}
+
+	nodeCount := other.nodeCount
+
+	if rbt.IsEmpty() {
+		rbt.setRoot(other.root())
+		rbt.leftmostNode = other.leftmostNode
+		rbt.rightmostNode = other.rightmostNode
+	} else {
+		minNode1, _ := rbt.GetMin()
+		maxNode1, _ := rbt.GetMax()
+		minNode2, _ := other.GetMin()
+		maxNode2, _ := other.GetMax()
+		lowTree, highTree, minNode := rbt, other, minNode2
+
+		if rbt.nodeOrderer(maxNode2, minNode1) && !rbt.nodeOrderer(maxNode1, minNode2) {
+			lowTree, highTree, minNode = other, rbt, minNode1
+		}
+
+		highTree.RemoveNode(minNode)
+		x, z := lowTree.root(), highTree.root()
+		lowTree.setRoot(nil)
+		highTree.setRoot(nil)
+		rbt.join(x, x.blackHeight(), minNode, z, z.blackHeight())
+		rbt.resetExtremeNodes()
+
+		// The removed node has been counted out of the high tree.
+		nodeCount = other.nodeCount + 1
+	}
+
+	rbt.nodeCount += nodeCount
+
+	rbt.adoptNodes()
+	other.reset()
+}
+
+// Union moves the nodes of the given other tree to the tree, except for
+// the nodes with keys equal to the keys of any nodes in the tree, which
+// are discarded, in O(n + m) time by merging the nodes of both trees in
+// order and rebuilding the tree.
+// The given callback, if not nil, is called with each discarded node in
+// order, after the tree has been rebuilt and the node has been reset.
+// The given other tree must be initialized in the same way as the tree,
+// and it becomes empty.
+func (rbt *RBTree) Union(other *RBTree, onDiscarded func(*RBTreeNode)) {
+	nodes1, nodes2 := rbt.collectNodes(), other.collectNodes()
+	nodes := make([]*RBTreeNode, 0, len(nodes1)+len(nodes2))
+	var discardedNodes []*RBTreeNode
+	var lastNode1 *RBTreeNode
+	i := 0
+
+	for _, y := range nodes2 {
+		for ; i < len(nodes1) && !rbt.isLess(y, nodes1[i]); i++ {
+			lastNode1 = nodes1[i]
+			nodes = append(nodes, lastNode1)
+		}
+
+		if lastNode1 != nil && !rbt.isLess(lastNode1, y) {
+			discardedNodes = append(discardedNodes, y)
+		} else {
+			nodes = append(nodes, y)
+		}
+	}
+
+	nodes = append(nodes, nodes1[i:]...)
+	rbt.rebuild(nodes, other, discardedNodes, onDiscarded)
+}
+
+// Intersect keeps the nodes with keys equal to the keys of any nodes in
+// the given other tree in the tree, and discards the rest of the nodes
+// of both trees, in O(n + m) time by merging the nodes of both trees in
+// order and rebuilding the tree.
+// The given callback, if not nil, is called with each discarded node in
+// order, after the tree has been rebuilt and the node has been reset.
+// The given other tree must be initialized in the same way as the tree,
+// and it becomes empty.
+func (rbt *RBTree) Intersect(other *RBTree, onDiscarded func(*RBTreeNode)) {
+	rbt.filter(other, true, onDiscarded)
+}
+
+// Difference keeps the nodes with keys not equal to the keys of any nodes
+// in the given other tree in the tree, and discards the rest of the nodes
+// of both trees, in O(n + m) time by merging the nodes of both trees in
+// order and rebuilding the tree.
+// The given callback, if not nil, is called with each discarded node in
+// order, after the tree has been rebuilt and the node has been reset.
+// The given other tree must be initialized in the same way as the tree,
+// and it becomes empty.
+func (rbt *RBTree) Difference(other *RBTree, onDiscarded func(*RBTreeNode)) {
+	rbt.filter(other, false, onDiscarded)
+}
+
+// RemoveRange removes nodes with keys within the given range from the
+// tree, by splitting the range off and joining the rest, in O(k + log n)
+// time, and then returns the number of removed nodes.
+// The range is bounded by the given minimum key and maximum key,
+// each of which is included or excluded as the given flags indicate.
+// The given callback, if not nil, is called with each removed node in
+// post-order, after the node has been removed and reset.
+func (rbt *RBTree) RemoveRange(minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool, onRemoved func(*RBTreeNode)) int {
+	var middle, high RBTree
+	middle.initFrom(rbt)
+	high.initFrom(rbt)
+	nodeCount := rbt.nodeCount
+	// Without order statistics enabled, the numbers of nodes are left
+	// inexact by the splits, and only the removed nodes are counted.
+	rbt.splitAt(minKey, minKeyIsInclusive, &middle)
+	middle.splitAt(maxKey, !maxKeyIsInclusive, &high)
+	rbt.Join(&high)
+	n := 0
+
+	middle.Clear(func(x *RBTreeNode) {
+		if onRemoved != nil {
+			onRemoved(x)
+		}
+
+		n++
+	})
+
+	rbt.nodeCount = nodeCount - n
+	return n
+}
+
 // GetRoot returns the root of the tree.
 // If the tree is empty, it returns false.
 func (rbt *RBTree) GetRoot() (*RBTreeNode, bool) {
@@ -121,56 +736,470 @@ func (rbt *RBTree) GetRoot() (*RBTreeNode, bool) {
 	return nil, false
 }
 
-// GetMin returns the node with the minimum key in the tree.
+// GetMin returns the node with the minimum key in the tree in O(1) time.
 // If the tree is empty, it returns false.
 func (rbt *RBTree) GetMin() (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.leftmostNode)
+}
+
+// GetMax returns the node with the maximum key in the tree in O(1) time.
+// If the tree is empty, it returns false.
+func (rbt *RBTree) GetMax() (*RBTreeNode, bool) {
+	return rbt.checkNode(rbt.rightmostNode)
+}
+
+// IsEmpty indicates whether the tree is empty.
+func (rbt *RBTree) IsEmpty() bool {
+	return rbt.root().isNull(rbt)
+}
+
+// NumberOfNodes returns the number of nodes in the tree.
+func (rbt *RBTree) NumberOfNodes() int {
+	return rbt.nodeCount
+}
+
+// Validate checks the integrity of the tree and then returns an error
+// telling where the first violation is found, if any.
+// The checks cover the links between parents and children, the colors
+// (no red node has a red child and every path from a node down to a leaf
+// has the same number of black nodes), the order of nodes, the subtree
+// sizes (if order statistics are enabled), the cached leftmost and
+// rightmost nodes and the number of nodes (if known).
+// Nodes are located by their paths from the root, such as "root.L.R"
+// for the right child of the left child of the root.
+func (rbt *RBTree) Validate() error {
+	return rbt.ValidateWithKeys(nil)
+}
+
+// ValidateWithKeys does what Validate does, and additionally checks that
+// the node comparer agrees with the node orderer by comparing every node
+// with the keys of itself and its adjacent nodes, which are retrieved with
+// the given getter.
+func (rbt *RBTree) ValidateWithKeys(nodeKeyGetter func(*RBTreeNode) interface{}) error {
+	rbtv := rbTreeValidator{
+		rbt:           rbt,
+		nodeKeyGetter: nodeKeyGetter,
+		path:          []byte("root"),
+	}
+
+	if root := rbt.root(); root != nil {
+		if root.parent != &rbt.header {
+			return rbtv.errorf("parent link is not the header")
+		}
+
+		if root.color != rbTreeNodeBlack {
+			return rbtv.errorf("red root")
+		}
+
+		if _, err := rbtv.validate(root); err != nil {
+			return err
+		}
+	}
+
+	if rbtv.firstNode != rbt.leftmostNode {
+		return fmt.Errorf("intrusive: rbtree: cached leftmost node is stale")
+	}
+
+	if rbtv.lastNode != rbt.rightmostNode {
+		return fmt.Errorf("intrusive: rbtree: cached rightmost node is stale")
+	}
+
+	if rbt.nodeCount != rbtv.nodeCount {
+		return fmt.Errorf("intrusive: rbtree: number of nodes is %d, want %d", rbt.nodeCount, rbtv.nodeCount)
+	}
+
+	return nil
+}
+
+func (rbt *RBTree) setRoot(root *RBTreeNode) {
+	rbt.header.setLeftChild(root)
+}
+
+func (rbt *RBTree) reset() {
+	rbt.setRoot(nil)
+	rbt.leftmostNode = nil
+	rbt.rightmostNode = nil
+	rbt.nodeCount = 0
+}
+
+// adoptNodes makes the tree own all nodes in it, which matters only in
+// debug mode.
+func (rbt *RBTree) adoptNodes() {
+	if !debugMode {
+		return
+	}
+
+	for x := rbt.leftmostNode; x != nil; x, _ = x.getNext(rbt) {
+		x.owner.detach()
+		x.owner.attach(unsafe.Pointer(rbt), "RBTreeNode")
+	}
+}
+
+func (rbt *RBTree) resetExtremeNodes() {
 	x := rbt.root()
 
+	if x.isNull(rbt) {
+		rbt.leftmostNode = nil
+		rbt.rightmostNode = nil
+		return
+	}
+
+	for y := x; ; y = y.leftChild {
+		if y.leftChild.isNull(rbt) {
+			rbt.leftmostNode = y
+			break
+		}
+	}
+
+	for y := x; ; y = y.rightChild {
+		if y.rightChild.isNull(rbt) {
+			rbt.rightmostNode = y
+			break
+		}
+	}
+}
+
+func (rbt *RBTree) findFirstNode(key interface{}, keyIsInclusive bool) *RBTreeNode {
+	return findFirstRBTreeNode(rbt, key, keyIsInclusive, rbt.nodeComparer)
+}
+
+func (rbt *RBTree) findLastNode(key interface{}, keyIsInclusive bool) *RBTreeNode {
+	return findLastRBTreeNode(rbt, key, keyIsInclusive, rbt.nodeComparer)
+}
+
+func (rbt *RBTree) rankFirstNode(key interface{}, keyIsInclusive bool) int {
+	x := rbt.root()
+	rank := 0
+
+	for !x.isNull(rbt) {
+		if d := rbt.nodeComparer(x, key); d > 0 || (d == 0 && keyIsInclusive) {
+			x = x.leftChild
+		} else {
+			rank += x.leftChild.getSize() + 1
+			x = x.rightChild
+		}
+	}
+
+	return rank
+}
+
+func (rbt *RBTree) checkOrderStatistics() {
+	if rbt.flags&rbTreeOrderStatistics == 0 {
+		panic("intrusive: order statistics of RBTree not enabled")
+	}
+}
+
+func (rbt *RBTree) adjustSizes(x *RBTreeNode, delta int32) {
+	for ; !x.isNull(rbt); x = x.parent {
+		x.size += delta
+	}
+}
+
+func (rbt *RBTree) rotateLeft(x *RBTreeNode) {
+	x.rotateLeft()
+	rbt.fixAfterNodeRotation(x)
+}
+
+func (rbt *RBTree) rotateRight(x *RBTreeNode) {
+	x.rotateRight()
+	rbt.fixAfterNodeRotation(x)
+}
+
+func (rbt *RBTree) fixAfterNodeRotation(x *RBTreeNode) {
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		x.parent.size = x.size
+		x.size = int32(x.leftChild.getSize() + x.rightChild.getSize() + 1)
+	}
+
+	if rbt.nodeAugmenter != nil {
+		rbt.augmentNode(x)
+		rbt.augmentNode(x.parent)
+	}
+}
+
+func (rbt *RBTree) propagateAugmentation(x *RBTreeNode, stop *RBTreeNode) {
+	for x != stop && !x.isNull(rbt) && rbt.augmentNode(x) {
+		x = x.parent
+	}
+}
+
+func (rbt *RBTree) augmentNode(x *RBTreeNode) bool {
+	leftChild, _ := rbt.checkNode(x.leftChild)
+	rightChild, _ := rbt.checkNode(x.rightChild)
+	return rbt.nodeAugmenter(x, leftChild, rightChild)
+}
+
+func (rbt *RBTree) checkNode(x *RBTreeNode) (*RBTreeNode, bool) {
 	if x.isNull(rbt) {
 		return nil, false
 	}
 
-	for {
-		y := x.leftChild
+	return x, true
+}
+
+func (rbt *RBTree) findNodeOrSlot(key interface{}) (*RBTreeNode, func(*RBTreeNode, *RBTreeNode), bool) {
+	return findRBTreeNodeOrSlot(rbt, key, rbt.nodeComparer)
+}
+
+func (rbt *RBTree) insertNode(x *RBTreeNode, y *RBTreeNode, f func(*RBTreeNode, *RBTreeNode)) {
+	x.owner.attach(unsafe.Pointer(rbt), "RBTreeNode")
+	x.leftChild = nil
+	x.rightChild = nil
+	x.color = rbTreeNodeRed
+	f(y, x)
+
+	if y == &rbt.header {
+		rbt.leftmostNode = x
+		rbt.rightmostNode = x
+	} else if x == y.leftChild {
+		if y == rbt.leftmostNode {
+			rbt.leftmostNode = x
+		}
+	} else {
+		if y == rbt.rightmostNode {
+			rbt.rightmostNode = x
+		}
+	}
+
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		x.size = 1
+		rbt.adjustSizes(y, 1)
+	}
+
+	if rbt.nodeAugmenter != nil {
+		rbt.augmentNode(x)
+		rbt.propagateAugmentation(y, &rbt.header)
+	}
+
+	rbt.fixAfterNodeInsertion(x)
+	rbt.nodeCount++
+}
+
+func (rbt *RBTree) build(nodes []*RBTreeNode, depth int, redNodeDepth int) *RBTreeNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	i := len(nodes) / 2
+	x := nodes[i]
+	x.owner.attach(unsafe.Pointer(rbt), "RBTreeNode")
+	x.setLeftChild(rbt.build(nodes[:i], depth+1, redNodeDepth))
+	x.setRightChild(rbt.build(nodes[i+1:], depth+1, redNodeDepth))
+
+	if depth == redNodeDepth {
+		x.color = rbTreeNodeRed
+	} else {
+		x.color = rbTreeNodeBlack
+	}
+
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		x.size = int32(len(nodes))
+	}
+
+	if rbt.nodeAugmenter != nil {
+		rbt.augmentNode(x)
+	}
+
+	return x
+}
+
+// isLess indicates whether the key of the given node 1 is less than the
+// key of the given node 2.
+func (rbt *RBTree) isLess(x *RBTreeNode, y *RBTreeNode) bool {
+	return rbt.nodeOrderer(x, y) && !rbt.nodeOrderer(y, x)
+}
+
+func (rbt *RBTree) collectNodes() []*RBTreeNode {
+	nodes := make([]*RBTreeNode, 0, rbt.NumberOfNodes())
+
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		nodes = append(nodes, it.Node())
+	}
+
+	return nodes
+}
+
+func (rbt *RBTree) filter(other *RBTree, keepsCommonNodes bool, onDiscarded func(*RBTreeNode)) {
+	nodes1, nodes2 := rbt.collectNodes(), other.collectNodes()
+	nodes := nodes1[:0] // filter in place
+	var discardedNodes []*RBTreeNode
+	j := 0
+
+	for _, x := range nodes1 {
+		for ; j < len(nodes2) && rbt.isLess(nodes2[j], x); j++ {
+			discardedNodes = append(discardedNodes, nodes2[j])
+		}
+
+		if isCommon := j < len(nodes2) && !rbt.isLess(x, nodes2[j]); isCommon == keepsCommonNodes {
+			nodes = append(nodes, x)
+		} else {
+			discardedNodes = append(discardedNodes, x)
+		}
+	}
+
+	discardedNodes = append(discardedNodes, nodes2[j:]...)
+	rbt.rebuild(nodes, other, discardedNodes, onDiscarded)
+}
+
+func (rbt *RBTree) rebuild(nodes []*RBTreeNode, other *RBTree, discardedNodes []*RBTreeNode, onDiscarded func(*RBTreeNode)) {
+	rbt.reset()
+	other.reset()
+
+	if debugMode {
+		for _, x := range nodes {
+			x.owner.detach()
+		}
+	}
+
+	rbt.BuildFromSorted(nodes)
+
+	for _, x := range discardedNodes {
+		*x = RBTreeNode{}
+
+		if onDiscarded != nil {
+			onDiscarded(x)
+		}
+	}
+}
+
+// initFrom initializes the tree in the same way as the given other tree.
+func (rbt *RBTree) initFrom(other *RBTree) *RBTree {
+	rbt.Init(other.nodeOrderer, other.nodeComparer)
+	rbt.nodeAugmenter = other.nodeAugmenter
+	rbt.flags = other.flags
+	return rbt
+}
+
+func (rbt *RBTree) splitAt(key interface{}, keyIsInclusive bool, other *RBTree) {
+	x := rbt.root()
+	y, _, z, _ := rbt.split(x, x.blackHeight(), key, keyIsInclusive, other)
+	rbt.setRoot(y)
+	rbt.resetExtremeNodes()
+	other.setRoot(z)
+	other.resetExtremeNodes()
+	other.adoptNodes()
+
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		rbt.nodeCount = y.getSize()
+		other.nodeCount = z.getSize()
+	}
+}
+
+// countNodesAfterSplit counts the nodes of the tree and the given other
+// tree split from the tree, which had the given number of nodes, by
+// walking through both trees from the split point at the same pace until
+// either of them ends, so it takes time in proportion to the number of
+// nodes of the smaller tree.
+func (rbt *RBTree) countNodesAfterSplit(nodeCount int, other *RBTree) {
+	x, y := rbt.rightmostNode, other.leftmostNode
+	n := 0
+
+	for {
+		if x == nil {
+			rbt.nodeCount = n
+			break
+		}
+
+		if y == nil {
+			rbt.nodeCount = nodeCount - n
+			break
+		}
+
+		x, _ = x.getPrev(rbt)
+		y, _ = y.getNext(other)
+		n++
+	}
+
+	other.nodeCount = nodeCount - rbt.nodeCount
+}
+
+func (rbt *RBTree) split(x *RBTreeNode, h int, key interface{}, keyIsInclusive bool, other *RBTree) (*RBTreeNode, int, *RBTreeNode, int) {
+	if x == nil {
+		return nil, 0, nil, 0
+	}
+
+	leftChild, h1 := x.leftChild.detach(h - 1)
+	rightChild, h2 := x.rightChild.detach(h - 1)
+
+	if d := rbt.nodeComparer(x, key); d > 0 || (d == 0 && keyIsInclusive) {
+		y, h3, z, h4 := rbt.split(leftChild, h1, key, keyIsInclusive, other)
+		z, h4 = other.join(z, h4, x, rightChild, h2)
+		return y, h3, z, h4
+	}
+
+	y, h3, z, h4 := rbt.split(rightChild, h2, key, keyIsInclusive, other)
+	y, h3 = rbt.join(leftChild, h1, x, y, h3)
+	return y, h3, z, h4
+}
+
+// join joins the given subtree x, node y and subtree z, in order, into the
+// tree, and then returns the root and the black height of the result.
+// The roots of the given subtrees must be black.
+func (rbt *RBTree) join(x *RBTreeNode, h1 int, y *RBTreeNode, z *RBTreeNode, h2 int) (*RBTreeNode, int) {
+	y.color = rbTreeNodeRed
+
+	if h1 >= h2 {
+		rbt.setRoot(x)
+		v, w, h := &rbt.header, x, h1
+
+		for h > h2 || !w.isBlack() {
+			if w.isBlack() {
+				h--
+			}
+
+			v, w = w, w.rightChild
+		}
+
+		y.setLeftChild(w)
+		y.setRightChild(z)
+
+		if v == &rbt.header {
+			rbt.setRoot(y)
+		} else {
+			v.setRightChild(y)
+		}
+	} else {
+		rbt.setRoot(z)
+		v, w, h := &rbt.header, z, h2
 
-		if y.isNull(rbt) {
-			return x, true
+		for h > h1 || !w.isBlack() {
+			if w.isBlack() {
+				h--
+			}
+
+			v, w = w, w.leftChild
 		}
 
-		x = y
+		y.setLeftChild(x)
+		y.setRightChild(w)
+		v.setLeftChild(y) // rbt.setRoot() if v is the header
 	}
-}
-
-// GetMax returns the node with the maximum key in the tree.
-// If the tree is empty, it returns false.
-func (rbt *RBTree) GetMax() (*RBTreeNode, bool) {
-	x := rbt.root()
 
-	if x.isNull(rbt) {
-		return nil, false
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		for v := y; !v.isNull(rbt); v = v.parent {
+			v.size = int32(v.leftChild.getSize() + v.rightChild.getSize() + 1)
+		}
 	}
 
-	for {
-		y := x.rightChild
+	if rbt.nodeAugmenter != nil {
+		rbt.augmentNode(y)
+		rbt.propagateAugmentation(y.parent, &rbt.header)
+	}
 
-		if y.isNull(rbt) {
-			return x, true
-		}
+	h := h1
 
-		x = y
+	if h2 > h {
+		h = h2
 	}
-}
 
-// IsEmpty indicates whether the tree is empty.
-func (rbt *RBTree) IsEmpty() bool {
-	return rbt.root().isNull(rbt)
-}
+	if rbt.fixAfterNodeInsertion(y) {
+		h++
+	}
 
-func (rbt *RBTree) setRoot(root *RBTreeNode) {
-	rbt.nil.setLeftChild(root)
+	return rbt.root(), h
 }
 
-func (rbt *RBTree) fixAfterNodeInsertion(x *RBTreeNode) {
+func (rbt *RBTree) fixAfterNodeInsertion(x *RBTreeNode) bool {
 	for {
 		y := x.parent
 
@@ -184,29 +1213,29 @@ func (rbt *RBTree) fixAfterNodeInsertion(x *RBTreeNode) {
 		if y == z.leftChild {
 			v = z.rightChild
 
-			if v.color == rbTreeNodeBlack {
+			if v.isBlack() {
 				if x == y.rightChild {
-					y.rotateLeft()
+					rbt.rotateLeft(y)
 					x, y = y, x
 				}
 
 				y.color = rbTreeNodeBlack
 				z.color = rbTreeNodeRed
-				z.rotateRight()
+				rbt.rotateRight(z)
 				break
 			}
 		} else {
 			v = z.leftChild
 
-			if v.color == rbTreeNodeBlack {
+			if v.isBlack() {
 				if x == y.leftChild {
-					y.rotateRight()
+					rbt.rotateRight(y)
 					x, y = y, x
 				}
 
 				y.color = rbTreeNodeBlack
 				z.color = rbTreeNodeRed
-				z.rotateLeft()
+				rbt.rotateLeft(z)
 				break
 			}
 		}
@@ -217,12 +1246,16 @@ func (rbt *RBTree) fixAfterNodeInsertion(x *RBTreeNode) {
 		x = z
 	}
 
-	rbt.root().color = rbTreeNodeBlack
+	if root := rbt.root(); root.color == rbTreeNodeRed {
+		root.color = rbTreeNodeBlack
+		return true
+	}
+
+	return false
 }
 
-func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
-	for x.color == rbTreeNodeBlack && x != rbt.root() {
-		y := x.parent
+func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode, y *RBTreeNode) {
+	for x != rbt.root() && x.isBlack() {
 		var z *RBTreeNode
 
 		if x == y.leftChild {
@@ -231,18 +1264,18 @@ func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
 			if z.color == rbTreeNodeRed {
 				y.color = rbTreeNodeRed
 				z.color = rbTreeNodeBlack
-				y.rotateLeft()
+				rbt.rotateLeft(y)
 				z = y.rightChild
 			}
 
 			v := z.rightChild
 			w := z.leftChild
 
-			if v.color == rbTreeNodeRed || w.color == rbTreeNodeRed {
-				if v.color == rbTreeNodeBlack {
+			if !v.isBlack() || !w.isBlack() {
+				if v.isBlack() {
 					z.color = rbTreeNodeRed
 					w.color = rbTreeNodeBlack
-					z.rotateRight()
+					rbt.rotateRight(z)
 					v = z
 					z = w
 				}
@@ -250,7 +1283,7 @@ func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
 				z.color = y.color
 				y.color = rbTreeNodeBlack
 				v.color = rbTreeNodeBlack
-				y.rotateLeft()
+				rbt.rotateLeft(y)
 				x = rbt.root()
 				break
 			}
@@ -260,18 +1293,18 @@ func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
 			if z.color == rbTreeNodeRed {
 				z.color = rbTreeNodeBlack
 				y.color = rbTreeNodeRed
-				y.rotateRight()
+				rbt.rotateRight(y)
 				z = y.leftChild
 			}
 
 			v := z.leftChild
 			w := z.rightChild
 
-			if v.color == rbTreeNodeRed || w.color == rbTreeNodeRed {
-				if v.color == rbTreeNodeBlack {
+			if !v.isBlack() || !w.isBlack() {
+				if v.isBlack() {
 					z.color = rbTreeNodeRed
 					w.color = rbTreeNodeBlack
-					z.rotateLeft()
+					rbt.rotateLeft(z)
 					v = z
 					z = w
 				}
@@ -279,7 +1312,7 @@ func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
 				z.color = y.color
 				y.color = rbTreeNodeBlack
 				v.color = rbTreeNodeBlack
-				y.rotateRight()
+				rbt.rotateRight(y)
 				x = rbt.root()
 				break
 			}
@@ -287,13 +1320,27 @@ func (rbt *RBTree) fixAfterNodeRemoval(x *RBTreeNode) {
 
 		z.color = rbTreeNodeRed
 		x = y
+		y = x.parent
+	}
+
+	if x != nil {
+		x.color = rbTreeNodeBlack
+	}
+}
+
+// nodeGoesBefore indicates whether the given node x, while being inserted,
+// goes before the given node y in the tree, where x goes before y with
+// an equal key unless stable insertion is enabled.
+func (rbt *RBTree) nodeGoesBefore(x *RBTreeNode, y *RBTreeNode) bool {
+	if rbt.flags&rbTreeStableInsertion != 0 {
+		return !rbt.nodeOrderer(y, x)
 	}
 
-	x.color = rbTreeNodeBlack
+	return rbt.nodeOrderer(x, y)
 }
 
 func (rbt *RBTree) root() *RBTreeNode {
-	return rbt.nil.leftChild
+	return rbt.header.leftChild
 }
 
 // RBTreeNodeOrderer is the type of a function indicating whether the
@@ -307,18 +1354,91 @@ type RBTreeNodeOrderer func(rbtn1 *RBTreeNode, rbtn2 *RBTreeNode) bool
 // with a value > 0 means the key of the node is greater than the given key;
 type RBTreeNodeComparer func(rbtn *RBTreeNode, key interface{}) int64
 
+// RBTreeNodeAugmenter is the type of a function recomputing the augmented
+// value of the given node from the node itself and the given children,
+// which are nil if absent, returning a boolean:
+// with a value == true means the augmented value has changed;
+// with a value == false means the augmented value remains unchanged,
+// so there is no need to update the augmented values of the ancestors.
+type RBTreeNodeAugmenter func(rbtn *RBTreeNode, leftChild *RBTreeNode, rightChild *RBTreeNode) bool
+
 // RBTreeNode represents a node in a red-black tree.
 type RBTreeNode struct {
+	owner      nodeOwner
 	parent     *RBTreeNode
 	leftChild  *RBTreeNode
 	rightChild *RBTreeNode
 	color      rbTreeNodeColor
+	size       int32 // shares a word with color on 64-bit platforms
+}
+
+// GetParent returns the parent of the node in the given tree.
+// If the node is the root of the tree, it returns false.
+func (rbtn *RBTreeNode) GetParent(rbt *RBTree) (*RBTreeNode, bool) {
+	rbtn.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	return rbt.checkNode(rbtn.parent)
+}
+
+// GetLeftChild returns the left child of the node in the given tree.
+// If the node has no left child, it returns false.
+func (rbtn *RBTreeNode) GetLeftChild(rbt *RBTree) (*RBTreeNode, bool) {
+	rbtn.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	return rbt.checkNode(rbtn.leftChild)
+}
+
+// GetRightChild returns the right child of the node in the given tree.
+// If the node has no right child, it returns false.
+func (rbtn *RBTreeNode) GetRightChild(rbt *RBTree) (*RBTreeNode, bool) {
+	rbtn.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	return rbt.checkNode(rbtn.rightChild)
 }
 
 // GetPrev returns the previous node to the node.
 // If the key of the node is the minimum key in the given tree,
 // it returns false.
 func (rbtn *RBTreeNode) GetPrev(rbt *RBTree) (*RBTreeNode, bool) {
+	rbtn.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	return rbtn.getPrev(rbt)
+}
+
+// GetNext returns the next node to the node.
+// If the key of the node is the maximum key in the given tree,
+// it returns false.
+func (rbtn *RBTreeNode) GetNext(rbt *RBTree) (*RBTreeNode, bool) {
+	rbtn.owner.check(unsafe.Pointer(rbt), "RBTreeNode")
+	return rbtn.getNext(rbt)
+}
+
+// GetContainer returns a pointer to the container which contains
+// the RBTreeNode field about the node at the given offset.
+func (rbtn *RBTreeNode) GetContainer(offset uintptr) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(unsafe.Pointer(rbtn)) - offset)
+}
+
+// IsReset indicates whether the node is reset (with a zero value).
+// As a node is reset once removed from a tree, it also indicates whether
+// the node is not in any tree.
+func (rbtn *RBTreeNode) IsReset() bool {
+	return rbtn.parent == nil
+}
+
+func (rbtn *RBTreeNode) setLeftChild(leftChild *RBTreeNode) {
+	rbtn.leftChild = leftChild
+
+	if leftChild != nil {
+		leftChild.parent = rbtn
+	}
+}
+
+func (rbtn *RBTreeNode) setRightChild(rightChild *RBTreeNode) {
+	rbtn.rightChild = rightChild
+
+	if rightChild != nil {
+		rightChild.parent = rbtn
+	}
+}
+
+func (rbtn *RBTreeNode) getPrev(rbt *RBTree) (*RBTreeNode, bool) {
 	if prev := rbtn.leftChild; !prev.isNull(rbt) {
 		for {
 			prevChild := prev.rightChild
@@ -348,10 +1468,7 @@ func (rbtn *RBTreeNode) GetPrev(rbt *RBTree) (*RBTreeNode, bool) {
 	}
 }
 
-// GetNext returns the next node to the node.
-// If the key of the node is the maximum key in the given tree,
-// it returns false.
-func (rbtn *RBTreeNode) GetNext(rbt *RBTree) (*RBTreeNode, bool) {
+func (rbtn *RBTreeNode) getNext(rbt *RBTree) (*RBTreeNode, bool) {
 	if next := rbtn.rightChild; !next.isNull(rbt) {
 		for {
 			nextChild := next.leftChild
@@ -381,27 +1498,6 @@ func (rbtn *RBTreeNode) GetNext(rbt *RBTree) (*RBTreeNode, bool) {
 	}
 }
 
-// GetContainer returns a pointer to the container which contains
-// the RBTreeNode field about the node at the given offset.
-func (rbtn *RBTreeNode) GetContainer(offset uintptr) unsafe.Pointer {
-	return unsafe.Pointer(uintptr(unsafe.Pointer(rbtn)) - offset)
-}
-
-// IsReset indicates whether the node is reset (with a zero value).
-func (rbtn *RBTreeNode) IsReset() bool {
-	return rbtn.parent == nil
-}
-
-func (rbtn *RBTreeNode) setLeftChild(leftChild *RBTreeNode) {
-	rbtn.leftChild = leftChild
-	leftChild.parent = rbtn
-}
-
-func (rbtn *RBTreeNode) setRightChild(rightChild *RBTreeNode) {
-	rbtn.rightChild = rightChild
-	rightChild.parent = rbtn
-}
-
 func (rbtn *RBTreeNode) replace(other *RBTreeNode) {
 	parent := rbtn.parent
 
@@ -427,59 +1523,213 @@ func (rbtn *RBTreeNode) rotateRight() {
 }
 
 func (rbtn *RBTreeNode) isNull(rbt *RBTree) bool {
-	return rbtn == &rbt.nil
+	return rbtn == nil || rbtn == &rbt.header
+}
+
+func (rbtn *RBTreeNode) isBlack() bool {
+	return rbtn == nil || rbtn.color == rbTreeNodeBlack
+}
+
+func (rbtn *RBTreeNode) blackHeight() int {
+	h := 0
+
+	for x := rbtn; x != nil; x = x.leftChild {
+		if x.color == rbTreeNodeBlack {
+			h++
+		}
+	}
+
+	return h
+}
+
+// detach makes the node with the given black height the black root of
+// a standalone subtree, and then returns the node and the black height
+// of the subtree.
+func (rbtn *RBTreeNode) detach(h int) (*RBTreeNode, int) {
+	if rbtn == nil || rbtn.color == rbTreeNodeBlack {
+		return rbtn, h
+	}
+
+	rbtn.color = rbTreeNodeBlack
+	return rbtn, h + 1
+}
+
+func (rbtn *RBTreeNode) getFirstPostOrderNode() *RBTreeNode {
+	x := rbtn
+
+	for {
+		if x.leftChild != nil {
+			x = x.leftChild
+		} else if x.rightChild != nil {
+			x = x.rightChild
+		} else {
+			return x
+		}
+	}
+}
+
+func (rbtn *RBTreeNode) getSize() int {
+	if rbtn == nil {
+		return 0
+	}
+
+	return int(rbtn.size)
 }
 
 // RBTreeIterator represents an iterator over all nodes in
 // a red-black tree.
+// It walks through the tree by parent links without allocation,
+// so it can be used as a value.
 type RBTreeIterator struct {
 	rbTreeIteratorBase
 }
 
 // Init initializes the iterator and then returns the iterator.
 func (rbti *RBTreeIterator) Init(rbt *RBTree) *RBTreeIterator {
-	rbti.rbt = rbt
-	rbti.stack = make([][2]*RBTreeNode, 0, initialRBTreeIteratorStackCapacity)
-	rbti.populateStack(rbt, rbt.root())
+	firstNode, ok := rbt.GetMin()
+
+	if !ok {
+		firstNode = &rbt.header
+	}
+
+	rbti.init(rbt, firstNode, &rbt.header, (*RBTreeNode).GetNext)
 	return rbti
 }
 
 // Advance advances the iterator to the next node.
 func (rbti *RBTreeIterator) Advance() {
-	rbti.populateStack(rbti.rbt, rbti.popStack()[1])
+	rbti.advance((*RBTreeNode).GetNext)
 }
 
-func (rbti *RBTreeIterator) populateStack(rbt *RBTree, x *RBTreeNode) {
-	for !x.isNull(rbt) {
-		rbti.stack = append(rbti.stack, [2]*RBTreeNode{x, x.rightChild})
-		x = x.leftChild
-	}
+// Node returns the current node in the iteration.
+// It's safe to remove the current node from the tree.
+// It's also safe to erase the current node for the next node
+// to advance to is pre-cached, and the erased node is
+// unlinked from the rest of the tree while advancing.
+// That will be useful to destroy the entire tree while
+// iterating through the tree, as long as every node is
+// erased once it's visited, after which the tree must be
+// initialized again before reuse.
+func (rbti *RBTreeIterator) Node() *RBTreeNode {
+	return rbti.node
 }
 
 // RBTreeReverseIterator represents an iterator over all nodes in
 // a red-black tree in reverse order.
+// It walks through the tree by parent links without allocation,
+// so it can be used as a value.
 type RBTreeReverseIterator struct {
 	rbTreeIteratorBase
 }
 
 // Init initializes the iterator and then returns the iterator.
 func (rbtri *RBTreeReverseIterator) Init(rbt *RBTree) *RBTreeReverseIterator {
-	rbtri.rbt = rbt
-	rbtri.stack = make([][2]*RBTreeNode, 0, initialRBTreeIteratorStackCapacity)
-	rbtri.populateStack(rbt, rbt.root())
+	firstNode, ok := rbt.GetMax()
+
+	if !ok {
+		firstNode = &rbt.header
+	}
+
+	rbtri.init(rbt, firstNode, &rbt.header, (*RBTreeNode).GetPrev)
 	return rbtri
 }
 
 // Advance advances the iterator to the next node.
 func (rbtri *RBTreeReverseIterator) Advance() {
-	rbtri.populateStack(rbtri.rbt, rbtri.popStack()[1])
+	rbtri.advance((*RBTreeNode).GetPrev)
 }
 
-func (rbtri *RBTreeReverseIterator) populateStack(rbt *RBTree, x *RBTreeNode) {
-	for !x.isNull(rbt) {
-		rbtri.stack = append(rbtri.stack, [2]*RBTreeNode{x, x.leftChild})
-		x = x.rightChild
+// Node returns the current node in the iteration.
+// It's safe to remove the current node from the tree.
+// It's also safe to erase the current node for the next node
+// to advance to is pre-cached, and the erased node is
+// unlinked from the rest of the tree while advancing.
+// That will be useful to destroy the entire tree while
+// iterating through the tree, as long as every node is
+// erased once it's visited, after which the tree must be
+// initialized again before reuse.
+func (rbtri *RBTreeReverseIterator) Node() *RBTreeNode {
+	return rbtri.node
+}
+
+// RBTreeRangeIterator represents an iterator over nodes with keys
+// within a range in a red-black tree.
+type RBTreeRangeIterator struct {
+	rbTreeIteratorBase
+}
+
+// Init initializes the iterator and then returns the iterator.
+func (rbtrai *RBTreeRangeIterator) Init(rbt *RBTree, minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool) *RBTreeRangeIterator {
+	firstNode := rbt.findFirstNode(minKey, minKeyIsInclusive)
+
+	if !firstNode.isNull(rbt) {
+		if d := rbt.nodeComparer(firstNode, maxKey); d > 0 || (d == 0 && !maxKeyIsInclusive) {
+			firstNode = &rbt.header
+		}
+	}
+
+	lastNode := rbt.findLastNode(maxKey, maxKeyIsInclusive)
+	rbtrai.init(rbt, firstNode, lastNode, (*RBTreeNode).GetNext)
+	return rbtrai
+}
+
+// Advance advances the iterator to the next node.
+func (rbtrai *RBTreeRangeIterator) Advance() {
+	rbtrai.advance((*RBTreeNode).GetNext)
+}
+
+// Node returns the current node in the iteration.
+// It's safe to remove the current node from the tree.
+// It's also safe to erase the current node for the next node
+// to advance to is pre-cached, and the erased node is
+// unlinked from the rest of the tree while advancing.
+// As long as every node is erased once it's visited, that will
+// be useful to destroy the nodes within the range, after which
+// the nodes out of the range are still linked to each other but
+// no longer balanced, so the tree must be initialized again
+// before reuse.
+func (rbtrai *RBTreeRangeIterator) Node() *RBTreeNode {
+	return rbtrai.node
+}
+
+// RBTreeRangeReverseIterator represents an iterator over nodes with keys
+// within a range in a red-black tree in reverse order.
+type RBTreeRangeReverseIterator struct {
+	rbTreeIteratorBase
+}
+
+// Init initializes the iterator and then returns the iterator.
+func (rbtrri *RBTreeRangeReverseIterator) Init(rbt *RBTree, minKey interface{}, maxKey interface{}, minKeyIsInclusive bool, maxKeyIsInclusive bool) *RBTreeRangeReverseIterator {
+	firstNode := rbt.findLastNode(maxKey, maxKeyIsInclusive)
+
+	if !firstNode.isNull(rbt) {
+		if d := rbt.nodeComparer(firstNode, minKey); d < 0 || (d == 0 && !minKeyIsInclusive) {
+			firstNode = &rbt.header
+		}
 	}
+
+	lastNode := rbt.findFirstNode(minKey, minKeyIsInclusive)
+	rbtrri.init(rbt, firstNode, lastNode, (*RBTreeNode).GetPrev)
+	return rbtrri
+}
+
+// Advance advances the iterator to the next node.
+func (rbtrri *RBTreeRangeReverseIterator) Advance() {
+	rbtrri.advance((*RBTreeNode).GetPrev)
+}
+
+// Node returns the current node in the iteration.
+// It's safe to remove the current node from the tree.
+// It's also safe to erase the current node for the next node
+// to advance to is pre-cached, and the erased node is
+// unlinked from the rest of the tree while advancing.
+// As long as every node is erased once it's visited, that will
+// be useful to destroy the nodes within the range, after which
+// the nodes out of the range are still linked to each other but
+// no longer balanced, so the tree must be initialized again
+// before reuse.
+func (rbtrri *RBTreeRangeReverseIterator) Node() *RBTreeNode {
+	return rbtrri.node
 }
 
 const (
@@ -487,31 +1737,235 @@ const (
 	rbTreeNodeBlack
 )
 
-const initialRBTreeIteratorStackCapacity = 64
+const (
+	rbTreeOrderStatistics = rbTreeFlags(1 << iota)
+	rbTreeStableInsertion
+)
 
-type rbTreeNodeColor int
+type rbTreeNodeColor int32
+
+type rbTreeFlags int
 
 type rbTreeIteratorBase struct {
-	rbt   *RBTree
-	stack [][2]*RBTreeNode
+	rbt                                       *RBTree
+	node, nextNode, lastNode                  *RBTreeNode
+	nodeParent, nodeLeftChild, nodeRightChild *RBTreeNode
 }
 
 // IsAtEnd indicates whether the iteration has no more nodes.
 func (rbtib *rbTreeIteratorBase) IsAtEnd() bool {
-	return len(rbtib.stack) == 0
+	return rbtib.node.isNull(rbtib.rbt)
 }
 
-// Node returns the current node in the iteration.
-// It's safe to erase the current node for the next node
-// to advance to is pre-cached. That will be useful to
-// destroy the entire tree while iterating through the tree.
-func (rbtib *rbTreeIteratorBase) Node() *RBTreeNode {
-	return rbtib.stack[len(rbtib.stack)-1][0]
-}
-
-func (rbtib *rbTreeIteratorBase) popStack() [2]*RBTreeNode {
-	i := len(rbtib.stack) - 1
-	stackTop := rbtib.stack[i]
-	rbtib.stack = rbtib.stack[:i]
-	return stackTop
+func (rbtib *rbTreeIteratorBase) init(rbt *RBTree, firstNode *RBTreeNode, lastNode *RBTreeNode, nodeStepper rbTreeNodeStepper) {
+	rbtib.rbt = rbt
+	rbtib.lastNode = lastNode
+	rbtib.setNode(firstNode, nodeStepper)
+}
+
+func (rbtib *rbTreeIteratorBase) advance(nodeStepper rbTreeNodeStepper) {
+	rbtib.unlinkErasedNode()
+	rbtib.setNode(rbtib.nextNode, nodeStepper)
+}
+
+func (rbtib *rbTreeIteratorBase) setNode(node *RBTreeNode, nodeStepper rbTreeNodeStepper) {
+	rbtib.node = node
+
+	if node.isNull(rbtib.rbt) {
+		rbtib.nodeParent, rbtib.nodeLeftChild, rbtib.nodeRightChild = nil, nil, nil
+	} else {
+		// Keep the links of the node in case the node is erased.
+		rbtib.nodeParent, rbtib.nodeLeftChild, rbtib.nodeRightChild = node.parent, node.leftChild, node.rightChild
+	}
+
+	rbtib.nextNode = rbtib.getNextNode(nodeStepper)
+}
+
+// unlinkErasedNode makes the parent of the current node, if the current
+// node has been erased in place, adopt the children of the current node,
+// so that the nodes left behind no longer link to the erased node.
+// The children are merged by hanging the left one under the leftmost
+// node of the right one, which keeps the nodes in order but not balanced.
+func (rbtib *rbTreeIteratorBase) unlinkErasedNode() {
+	x, y := rbtib.node, rbtib.nodeParent
+
+	if y == nil || x.parent != nil {
+		// Either the current node is null or it hasn't been erased.
+		return
+	}
+
+	// The current node has been either erased or removed. Unlike a
+	// removed node, an erased node is still linked by its parent.
+	if y.leftChild != x && y.rightChild != x {
+		return
+	}
+
+	z := rbtib.nodeRightChild
+
+	if w := rbtib.nodeLeftChild; z == nil {
+		z = w
+	} else if w != nil {
+		v := z
+
+		for v.leftChild != nil {
+			v = v.leftChild
+		}
+
+		v.setLeftChild(w)
+	}
+
+	if y.leftChild == x {
+		y.setLeftChild(z)
+	} else {
+		y.setRightChild(z)
+	}
+}
+
+func (rbtib *rbTreeIteratorBase) getNextNode(nodeStepper rbTreeNodeStepper) *RBTreeNode {
+	rbt := rbtib.rbt
+	node := rbtib.node
+
+	if node.isNull(rbt) || node == rbtib.lastNode {
+		return &rbt.header
+	}
+
+	nextNode, ok := nodeStepper(node, rbt)
+
+	if !ok {
+		return &rbt.header
+	}
+
+	return nextNode
+}
+
+type rbTreeNodeStepper func(*RBTreeNode, *RBTree) (*RBTreeNode, bool)
+
+type rbTreeValidator struct {
+	rbt           *RBTree
+	nodeKeyGetter func(*RBTreeNode) interface{}
+	path          []byte
+	firstNode     *RBTreeNode
+	lastNode      *RBTreeNode
+	nodeCount     int
+}
+
+// validate validates the subtree rooted at the given node, whose parent
+// link has been checked, and then returns the black height of the subtree.
+func (rbtv *rbTreeValidator) validate(x *RBTreeNode) (int, error) {
+	rbt := rbtv.rbt
+
+	if x.color != rbTreeNodeRed && x.color != rbTreeNodeBlack {
+		return 0, rbtv.errorf("invalid color %d", x.color)
+	}
+
+	if debugMode && x.owner.getContainer() != unsafe.Pointer(rbt) {
+		return 0, rbtv.errorf("not owned by the tree")
+	}
+
+	h1, err := rbtv.validateChild(x, x.leftChild, ".L")
+
+	if err != nil {
+		return 0, err
+	}
+
+	if y := rbtv.lastNode; y == nil {
+		rbtv.firstNode = x
+	} else if rbt.isLess(x, y) {
+		return 0, rbtv.errorf("less than the previous node")
+	}
+
+	if err := rbtv.compare(rbtv.lastNode, x); err != nil {
+		return 0, err
+	}
+
+	rbtv.lastNode = x
+	rbtv.nodeCount++
+	h2, err := rbtv.validateChild(x, x.rightChild, ".R")
+
+	if err != nil {
+		return 0, err
+	}
+
+	if h1 != h2 {
+		return 0, rbtv.errorf("black heights of subtrees differ: %d (left) vs %d (right)", h1, h2)
+	}
+
+	if rbt.flags&rbTreeOrderStatistics != 0 {
+		if size := 1 + x.leftChild.getSize() + x.rightChild.getSize(); x.getSize() != size {
+			return 0, rbtv.errorf("size is %d, want %d", x.size, size)
+		}
+	}
+
+	if x.color == rbTreeNodeBlack {
+		h1++
+	}
+
+	return h1, nil
+}
+
+func (rbtv *rbTreeValidator) validateChild(x *RBTreeNode, y *RBTreeNode, pathStep string) (int, error) {
+	if y == nil {
+		return 0, nil
+	}
+
+	rbtv.path = append(rbtv.path, pathStep...)
+
+	if y == &rbtv.rbt.header {
+		return 0, rbtv.errorf("header linked as a child")
+	}
+
+	if y.parent != x {
+		return 0, rbtv.errorf("parent link disagrees with child link")
+	}
+
+	if x.color == rbTreeNodeRed && y.color == rbTreeNodeRed {
+		return 0, rbtv.errorf("red node with red parent")
+	}
+
+	h, err := rbtv.validate(y)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rbtv.path = rbtv.path[:len(rbtv.path)-len(pathStep)]
+	return h, nil
+}
+
+// compare checks that the node comparer agrees with the node orderer on
+// the given current node y and the given previous node x, which is nil if
+// the current node is the first one.
+func (rbtv *rbTreeValidator) compare(x *RBTreeNode, y *RBTreeNode) error {
+	if rbtv.nodeKeyGetter == nil {
+		return nil
+	}
+
+	rbt := rbtv.rbt
+	yKey := rbtv.nodeKeyGetter(y)
+
+	if rbt.nodeComparer(y, yKey) != 0 {
+		return rbtv.errorf("node comparer disagrees with node orderer on the node itself")
+	}
+
+	if x == nil {
+		return nil
+	}
+
+	d1, d2 := rbt.nodeComparer(x, yKey), rbt.nodeComparer(y, rbtv.nodeKeyGetter(x))
+
+	if rbt.isLess(x, y) {
+		if d1 < 0 && d2 > 0 {
+			return nil
+		}
+	} else {
+		if d1 == 0 && d2 == 0 {
+			return nil
+		}
+	}
+
+	return rbtv.errorf("node comparer disagrees with node orderer on the previous node")
+}
+
+func (rbtv *rbTreeValidator) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("intrusive: rbtree node %s: %s", rbtv.path, fmt.Sprintf(format, args...))
 }
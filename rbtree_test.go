@@ -3,7 +3,11 @@ package intrusive_test
 import (
 	"bytes"
 	"fmt"
+	"iter"
+	"math"
 	"math/rand"
+	"sort"
+	"strings"
 	"testing"
 	"unsafe"
 
@@ -117,7 +121,9 @@ func TestRBTreeRemoveNode(t *testing.T) {
 		assert.True(t, ok)
 		for _, v := range tt.In {
 			r := &rs[v-1]
+			assert.False(t, r.RBTreeNode.IsReset(), "case %d", i)
 			rbt.RemoveNode(&r.RBTreeNode)
+			assert.True(t, r.RBTreeNode.IsReset(), "case %d", i)
 		}
 		if tt.OutIsReverse {
 			assert.Equal(t, tt.Out, dumpReverseRecordRBTree(rbt), "case %d", i)
@@ -170,6 +176,427 @@ func TestRBTreeFindNode(t *testing.T) {
 	}
 }
 
+func TestRBTreeInsertNodeWithHint(t *testing.T) {
+	var n int
+	order := func(node1 *intrusive.RBTreeNode, node2 *intrusive.RBTreeNode) bool {
+		n++
+		return orderRBTreeNodeOfRecord(node1, node2)
+	}
+	rbt := new(intrusive.RBTree).Init(order, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+	var rs [3000]recordOfRBTree
+	r := &rs[1000]
+	r.Value = 1000
+	rbt.InsertNode(&r.RBTreeNode)
+	for i := 1001; i < 2000; i++ {
+		r := &rs[i]
+		r.Value = i
+		rbt.InsertNodeAfter(&r.RBTreeNode, &rs[i-1].RBTreeNode)
+	}
+	for i := 999; i >= 0; i-- {
+		r := &rs[i]
+		r.Value = i
+		rbt.InsertNodeBefore(&r.RBTreeNode, &rs[i+1].RBTreeNode)
+	}
+	assert.LessOrEqual(t, n, 2*2000)
+	for i := 2000; i < len(rs); i++ {
+		r := &rs[i]
+		r.Value = i
+		hint := &rs[rand.Intn(i)].RBTreeNode
+		if i%2 == 0 {
+			rbt.InsertNodeBefore(&r.RBTreeNode, hint)
+		} else {
+			rbt.InsertNodeAfter(&r.RBTreeNode, hint)
+		}
+	}
+	checkRBTreeDepth(t, rbt)
+	var i int
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		assert.Equal(t, &rs[i].RBTreeNode, it.Node())
+		assert.Equal(t, i, rbt.GetRank(it.Node()))
+		i++
+	}
+	assert.Equal(t, len(rs), i)
+}
+
+func TestRBTreeInsertNodeUnique(t *testing.T) {
+	for i, tt := range []struct {
+		In  []int
+		Out string
+	}{
+		{
+			In:  []int{},
+			Out: "",
+		},
+		{
+			In:  []int{3, 1, 2, 1, 3, 3},
+			Out: "1,2,3",
+		},
+		{
+			In:  []int{6, 5, 4, 3, 2, 1, 6, 5, 4, 3, 2, 1},
+			Out: "1,2,3,4,5,6",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		rs := make(map[int]*recordOfRBTree)
+		for _, v := range tt.In {
+			r := &recordOfRBTree{Value: v}
+			rbtn, ok := rbt.InsertNodeUnique(&r.RBTreeNode, v)
+			if r2, ok2 := rs[v]; ok2 {
+				assert.False(t, ok, "case %d", i)
+				assert.Equal(t, &r2.RBTreeNode, rbtn, "case %d", i)
+			} else {
+				assert.True(t, ok, "case %d", i)
+				assert.Equal(t, &r.RBTreeNode, rbtn, "case %d", i)
+				rs[v] = r
+			}
+		}
+		assert.Equal(t, len(rs), rbt.NumberOfNodes(), "case %d", i)
+		assert.Equal(t, tt.Out, dumpRecordRBTree(rbt), "case %d", i)
+	}
+}
+
+func TestRBTreeFindOrInsertNode(t *testing.T) {
+	for i, tt := range []struct {
+		In  []int
+		Out string
+	}{
+		{
+			In:  []int{},
+			Out: "",
+		},
+		{
+			In:  []int{3, 1, 2, 1, 3, 3},
+			Out: "3,2,1",
+		},
+		{
+			In:  []int{1, 2, 3, 4, 5, 6, 6, 5, 4, 3, 2, 1},
+			Out: "6,5,4,3,2,1",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		var n int
+		for _, v := range tt.In {
+			rbtn, ok := rbt.FindOrInsertNode(v, func() *intrusive.RBTreeNode {
+				n++
+				return &(&recordOfRBTree{Value: v}).RBTreeNode
+			})
+			rbtn2, _ := rbt.FindNode(v)
+			assert.Equal(t, rbtn2, rbtn, "case %d", i)
+			if ok {
+				assert.Equal(t, n, rbt.NumberOfNodes(), "case %d", i)
+			}
+		}
+		assert.Equal(t, n, rbt.NumberOfNodes(), "case %d", i)
+		assert.Equal(t, tt.Out, dumpReverseRecordRBTree(rbt), "case %d", i)
+	}
+}
+
+func TestRBTreeUpdateNode(t *testing.T) {
+	for i, tt := range []struct {
+		In      []int
+		Updates [][2]int
+		Moved   []bool
+		Out     string
+	}{
+		{
+			In:      []int{1, 3, 5, 7, 9},
+			Updates: [][2]int{{1, 4}, {0, 0}, {4, 8}},
+			Moved:   []bool{false, false, false},
+			Out:     "0,4,5,7,8",
+		},
+		{
+			In:      []int{1, 3, 5, 7, 9},
+			Updates: [][2]int{{0, 6}, {4, 2}, {2, 10}},
+			Moved:   []bool{true, true, true},
+			Out:     "2,3,6,7,10",
+		},
+		{
+			In:      []int{1, 3, 5},
+			Updates: [][2]int{{1, 2}, {1, 4}, {1, 6}},
+			Moved:   []bool{false, false, true},
+			Out:     "1,5,6",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		rs := make([]recordOfRBTree, len(tt.In))
+		for j, v := range tt.In {
+			rs[j].Value = v
+			rbt.InsertNode(&rs[j].RBTreeNode)
+		}
+		for j, u := range tt.Updates {
+			rs[u[0]].Value = u[1]
+			assert.Equal(t, tt.Moved[j], rbt.UpdateNode(&rs[u[0]].RBTreeNode), "case %d", i)
+		}
+		checkRBTreeDepth(t, rbt)
+		assert.Equal(t, len(tt.In), rbt.NumberOfNodes(), "case %d", i)
+		assert.Equal(t, tt.Out, dumpRecordRBTree(rbt), "case %d", i)
+	}
+}
+
+func TestRBTreeReplaceNode(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [100]recordOfRBTree
+	for i := range rs {
+		rs[i].Value = i * 2
+		rbt.InsertNode(&rs[i].RBTreeNode)
+	}
+	var rs2 [len(rs)]recordOfRBTree
+	for i := range rs2 {
+		if i%3 != 0 {
+			continue
+		}
+		rs2[i].Value = i*2 + 1
+		rbt.ReplaceNode(&rs[i].RBTreeNode, &rs2[i].RBTreeNode)
+		rbtn, ok := rbt.FindNode(i*2 + 1)
+		if assert.True(t, ok, "case %d", i) {
+			assert.Equal(t, &rs2[i].RBTreeNode, rbtn, "case %d", i)
+		}
+		_, ok = rbt.FindNode(i * 2)
+		assert.False(t, ok, "case %d", i)
+	}
+	checkRBTreeDepth(t, rbt)
+	assert.Equal(t, len(rs), rbt.NumberOfNodes())
+	j := 0
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		v := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode))).Value
+		if j%3 == 0 {
+			assert.Equal(t, j*2+1, v)
+		} else {
+			assert.Equal(t, j*2, v)
+		}
+		j++
+	}
+	assert.Equal(t, len(rs), j)
+}
+
+func TestRBTreeEnableStableInsertion(t *testing.T) {
+	for i, tt := range []struct {
+		In                        []int
+		IsStable, OrderStatistics bool
+		Out                       string
+	}{
+		{
+			In:  []int{2, 1, 2, 1, 2},
+			Out: "3,1,4,2,0",
+		},
+		{
+			In:       []int{2, 1, 2, 1, 2},
+			IsStable: true,
+			Out:      "1,3,0,2,4",
+		},
+		{
+			In:              []int{3, 3, 3, 1, 1, 2, 3},
+			IsStable:        true,
+			OrderStatistics: true,
+			Out:             "3,4,5,0,1,2,6",
+		},
+	} {
+		order := func(node1 *intrusive.RBTreeNode, node2 *intrusive.RBTreeNode) bool {
+			return !orderRBTreeNodeOfRecord(node2, node1) // not greater than
+		}
+		rbt := new(intrusive.RBTree).Init(order, compareRBTreeNodeOfRecrod)
+		if tt.IsStable {
+			rbt.EnableStableInsertion()
+		}
+		if tt.OrderStatistics {
+			rbt.EnableOrderStatistics()
+		}
+		rs := make([]recordOfRBTree, len(tt.In))
+		for i, v := range tt.In {
+			rs[i].Value = v
+			rbt.InsertNode(&rs[i].RBTreeNode)
+		}
+		var ids []string
+		for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+			r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+			ids = append(ids, fmt.Sprint((uintptr(unsafe.Pointer(r))-uintptr(unsafe.Pointer(&rs[0])))/unsafe.Sizeof(rs[0])))
+		}
+		assert.Equal(t, tt.Out, strings.Join(ids, ","), "case %d", i)
+		for v := 0; v <= 4; v++ {
+			var n int
+			for _, v2 := range tt.In {
+				if v2 == v {
+					n++
+				}
+			}
+			assert.Equal(t, n, rbt.CountEqual(v), "case %d", i)
+			firstNode, lastNode, ok := rbt.FindEqualRange(v)
+			if n == 0 {
+				assert.False(t, ok, "case %d", i)
+				continue
+			}
+			if assert.True(t, ok, "case %d", i) {
+				prev, ok := firstNode.GetPrev(rbt)
+				assert.True(t, !ok || compareRBTreeNodeOfRecrod(prev, v) < 0, "case %d", i)
+				next, ok := lastNode.GetNext(rbt)
+				assert.True(t, !ok || compareRBTreeNodeOfRecrod(next, v) > 0, "case %d", i)
+				assert.Equal(t, int64(0), compareRBTreeNodeOfRecrod(firstNode, v), "case %d", i)
+				assert.Equal(t, int64(0), compareRBTreeNodeOfRecrod(lastNode, v), "case %d", i)
+			}
+		}
+	}
+}
+
+func TestRBTreeInsertNodeWithHintStably(t *testing.T) {
+	order := func(node1 *intrusive.RBTreeNode, node2 *intrusive.RBTreeNode) bool {
+		return !orderRBTreeNodeOfRecord(node2, node1) // not greater than
+	}
+	rbt := new(intrusive.RBTree).Init(order, compareRBTreeNodeOfRecrod).EnableStableInsertion()
+	rs := []recordOfRBTree{{Value: 1}, {Value: 2}, {Value: 1}, {Value: 1}, {Value: 2}, {Value: 2}}
+	rbt.InsertNode(&rs[0].RBTreeNode)
+	rbt.InsertNode(&rs[1].RBTreeNode)
+	rbt.InsertNodeBefore(&rs[2].RBTreeNode, &rs[1].RBTreeNode)
+	rbt.InsertNodeAfter(&rs[3].RBTreeNode, &rs[0].RBTreeNode)
+	rbt.InsertNodeBefore(&rs[4].RBTreeNode, &rs[1].RBTreeNode)
+	rbt.InsertNodeAfter(&rs[5].RBTreeNode, &rs[0].RBTreeNode)
+	var ids []string
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+		ids = append(ids, fmt.Sprint((uintptr(unsafe.Pointer(r))-uintptr(unsafe.Pointer(&rs[0])))/unsafe.Sizeof(rs[0])))
+	}
+	assert.Equal(t, "0,2,3,1,4,5", strings.Join(ids, ","))
+	assert.NoError(t, rbt.Validate())
+	assert.PanicsWithValue(t, "intrusive: stable insertion enabled for non-empty RBTree", func() { rbt.EnableStableInsertion() })
+}
+
+func TestRBTreeFindBounds(t *testing.T) {
+	for i, tt := range []struct {
+		In  []int
+		Out [][4]int
+	}{
+		{
+			In:  []int{0, 1, 2, 3, 4, 5, 6},
+			Out: [][4]int{{0, 0, -1, 0}, {0, 1, 0, 0}, {1, 4, 3, 1}, {4, 4, 3, 4}, {4, 5, 4, 4}, {5, 6, 5, 5}, {6, -1, 6, 6}},
+		},
+		{
+			In:  []int{-100, 100},
+			Out: [][4]int{{0, 0, -1, 0}, {-1, -1, 6, -1}},
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		_, ok := rbt.FindLowerBound(0)
+		assert.False(t, ok)
+		rs := [...]recordOfRBTree{{Value: 1}, {Value: 2}, {Value: 2}, {Value: 2}, {Value: 4}, {Value: 5}, {Value: 6}}
+		for i := range rs {
+			rbt.InsertNode(&rs[i].RBTreeNode)
+		}
+		out := make([][4]int, len(tt.In))
+		for i, v := range tt.In {
+			for j, f := range [...]func(interface{}) (*intrusive.RBTreeNode, bool){
+				rbt.FindLowerBound,
+				rbt.FindUpperBound,
+				rbt.FindFloor,
+				rbt.FindCeiling,
+			} {
+				rbtn, ok := f(v)
+				out[i][j] = -1
+				if ok {
+					r := (*recordOfRBTree)(rbtn.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+					out[i][j] = int(uintptr(unsafe.Pointer(r))-uintptr(unsafe.Pointer(&rs[0]))) / int(unsafe.Sizeof(rs[0]))
+				}
+			}
+		}
+		assert.Equal(t, tt.Out, out, "case %d", i)
+	}
+}
+
+func TestRBTreeForeachRange(t *testing.T) {
+	for i, tt := range []struct {
+		MinKey, MaxKey                       int
+		MinKeyIsInclusive, MaxKeyIsInclusive bool
+		Out                                  string
+	}{
+		{MinKey: 0, MaxKey: 100, Out: "1,2,3,4,5,6,7,8,9,10"},
+		{MinKey: 3, MaxKey: 7, Out: "4,5,6"},
+		{MinKey: 3, MaxKey: 7, MinKeyIsInclusive: true, Out: "3,4,5,6"},
+		{MinKey: 3, MaxKey: 7, MaxKeyIsInclusive: true, Out: "4,5,6,7"},
+		{MinKey: 3, MaxKey: 7, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, Out: "3,4,5,6,7"},
+		{MinKey: 5, MaxKey: 5, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, Out: "5"},
+		{MinKey: 5, MaxKey: 5, MinKeyIsInclusive: true, Out: ""},
+		{MinKey: 5, MaxKey: 6, Out: ""},
+		{MinKey: 7, MaxKey: 3, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, Out: ""},
+		{MinKey: 10, MaxKey: 100, MinKeyIsInclusive: true, Out: "10"},
+		{MinKey: 11, MaxKey: 100, MinKeyIsInclusive: true, Out: ""},
+		{MinKey: -100, MaxKey: 1, Out: ""},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		var rs [10]recordOfRBTree
+		for i := range rs {
+			r := &rs[i]
+			r.Value = i + 1
+			rbt.InsertNode(&r.RBTreeNode)
+		}
+		var buffer bytes.Buffer
+		for it := rbt.ForeachRange(tt.MinKey, tt.MaxKey, tt.MinKeyIsInclusive, tt.MaxKeyIsInclusive); !it.IsAtEnd(); it.Advance() {
+			r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+			fmt.Fprintf(&buffer, "%v,", r.Value)
+		}
+		var values []string
+		for it := rbt.ForeachRangeReverse(tt.MinKey, tt.MaxKey, tt.MinKeyIsInclusive, tt.MaxKeyIsInclusive); !it.IsAtEnd(); it.Advance() {
+			r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+			values = append([]string{fmt.Sprint(r.Value)}, values...)
+			rbt.RemoveNode(it.Node())
+		}
+		assert.Equal(t, tt.Out, strings.TrimSuffix(buffer.String(), ","), "case %d", i)
+		assert.Equal(t, tt.Out, strings.Join(values, ","), "case %d", i)
+		assert.Equal(t, len(rs)-len(values), rbt.NumberOfNodes(), "case %d", i)
+	}
+}
+
+func TestRBTreeRemoveRange(t *testing.T) {
+	for i, tt := range []struct {
+		MinKey, MaxKey                       int
+		MinKeyIsInclusive, MaxKeyIsInclusive bool
+		OrderStatistics                      bool
+		Out1, Out2                           string
+	}{
+		{MinKey: 0, MaxKey: 100, Out1: "1,2,3,4,5,6,7,8,9,10", Out2: ""},
+		{MinKey: 3, MaxKey: 7, Out1: "4,5,6", Out2: "1,2,3,7,8,9,10"},
+		{MinKey: 3, MaxKey: 7, MinKeyIsInclusive: true, OrderStatistics: true, Out1: "3,4,5,6", Out2: "1,2,7,8,9,10"},
+		{MinKey: 3, MaxKey: 7, MaxKeyIsInclusive: true, Out1: "4,5,6,7", Out2: "1,2,3,8,9,10"},
+		{MinKey: 3, MaxKey: 7, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, OrderStatistics: true, Out1: "3,4,5,6,7", Out2: "1,2,8,9,10"},
+		{MinKey: 5, MaxKey: 5, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, Out1: "5", Out2: "1,2,3,4,6,7,8,9,10"},
+		{MinKey: 5, MaxKey: 5, MinKeyIsInclusive: true, Out1: "", Out2: "1,2,3,4,5,6,7,8,9,10"},
+		{MinKey: 7, MaxKey: 3, MinKeyIsInclusive: true, MaxKeyIsInclusive: true, OrderStatistics: true, Out1: "", Out2: "1,2,3,4,5,6,7,8,9,10"},
+		{MinKey: 10, MaxKey: 100, MinKeyIsInclusive: true, Out1: "10", Out2: "1,2,3,4,5,6,7,8,9"},
+		{MinKey: -100, MaxKey: 1, MaxKeyIsInclusive: true, OrderStatistics: true, Out1: "1", Out2: "2,3,4,5,6,7,8,9,10"},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		if tt.OrderStatistics {
+			rbt.EnableOrderStatistics()
+		}
+		var rs [10]recordOfRBTree
+		for i := range rs {
+			r := &rs[i]
+			r.Value = i + 1
+			rbt.InsertNode(&r.RBTreeNode)
+		}
+		var vs []int
+		n := rbt.RemoveRange(tt.MinKey, tt.MaxKey, tt.MinKeyIsInclusive, tt.MaxKeyIsInclusive, func(rbtn *intrusive.RBTreeNode) {
+			assert.True(t, rbtn.IsReset(), "case %d", i)
+			r := (*recordOfRBTree)(rbtn.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+			vs = append(vs, r.Value)
+		})
+		sort.Ints(vs)
+		var values []string
+		for _, v := range vs {
+			values = append(values, fmt.Sprint(v))
+		}
+		assert.Equal(t, len(values), n, "case %d", i)
+		assert.Equal(t, tt.Out1, strings.Join(values, ","), "case %d", i)
+		assert.Equal(t, len(rs)-n, rbt.NumberOfNodes(), "case %d", i)
+		checkRBTreeDepth(t, rbt)
+		assert.NoError(t, rbt.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "case %d", i)
+		if tt.OrderStatistics {
+			for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+				rbtn, _ := rbt.GetNodeByRank(rbt.GetRank(it.Node()))
+				assert.Equal(t, it.Node(), rbtn, "case %d", i)
+			}
+		}
+		assert.Equal(t, tt.Out2, dumpRecordRBTree(rbt), "case %d", i)
+	}
+}
+
 func TestRBTreeGetMinMaxNodeGetPrevNext(t *testing.T) {
 	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
 	_, ok := rbt.GetMin()
@@ -208,6 +635,524 @@ func TestRBTreeGetMinMaxNodeGetPrevNext(t *testing.T) {
 	assert.Equal(t, -1, i)
 }
 
+func TestRBTreeGetMinMaxCached(t *testing.T) {
+	checkMinMax := func(rbt *intrusive.RBTree, msgAndArgs ...interface{}) {
+		min, minOk := rbt.GetMin()
+		max, maxOk := rbt.GetMax()
+		root, ok := rbt.GetRoot()
+		if !assert.Equal(t, ok, minOk, msgAndArgs...) || !assert.Equal(t, ok, maxOk, msgAndArgs...) || !ok {
+			return
+		}
+		min2, max2 := root, root
+		for x, ok := min2.GetLeftChild(rbt); ok; x, ok = x.GetLeftChild(rbt) {
+			min2 = x
+		}
+		for x, ok := max2.GetRightChild(rbt); ok; x, ok = x.GetRightChild(rbt) {
+			max2 = x
+		}
+		assert.Equal(t, min2, min, msgAndArgs...)
+		assert.Equal(t, max2, max, msgAndArgs...)
+	}
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	checkMinMax(rbt)
+	var rs [1000]recordOfRBTree
+	for i, j := range rand.Perm(len(rs)) {
+		rs[j].Value = j
+		rbt.InsertNode(&rs[j].RBTreeNode)
+		checkMinMax(rbt, "case %d", i)
+	}
+	for i := 0; i < len(rs)/2; i++ {
+		rbt.RemoveNode(&rs[i].RBTreeNode)
+		checkMinMax(rbt, "case %d", i)
+		rbt.RemoveNode(&rs[len(rs)-1-i].RBTreeNode)
+		checkMinMax(rbt, "case %d", i)
+	}
+	var rbts [2]intrusive.RBTree
+	rbts[0].Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	rbts[1].Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	rbtns := make([]*intrusive.RBTreeNode, len(rs))
+	for i := range rs {
+		rbtns[i] = &rs[i].RBTreeNode
+	}
+	rbts[0].BuildFromSorted(rbtns)
+	checkMinMax(&rbts[0])
+	for i, key := range []int{500, 0, 250, 1000, 750} {
+		rbt1, rbt2 := &rbts[i%2], &rbts[1-i%2]
+		rbt1.SplitAt(key, rbt2)
+		checkMinMax(rbt1, "case %d", i)
+		checkMinMax(rbt2, "case %d", i)
+		rbt2.Join(rbt1)
+		checkMinMax(rbt1, "case %d", i)
+		checkMinMax(rbt2, "case %d", i)
+		assert.NoError(t, rbt2.Validate(), "case %d", i)
+		assert.Equal(t, len(rs), rbt2.NumberOfNodes(), "case %d", i)
+	}
+}
+
+func TestRBTreeForeachAllocs(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [100]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = i + 1
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	var n int
+	allocs := testing.AllocsPerRun(10, func() {
+		for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+			n++
+		}
+		for it := rbt.ForeachReverse(); !it.IsAtEnd(); it.Advance() {
+			n++
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, 11*2*len(rs), n)
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		rbt.RemoveNode(it.Node())
+	}
+	assert.True(t, rbt.IsEmpty())
+}
+
+func TestRBTreeForeachErasingNodes(t *testing.T) {
+	type iterator interface {
+		IsAtEnd() bool
+		Node() *intrusive.RBTreeNode
+		Advance()
+	}
+	for i, n := range []int{0, 1, 2, 3, 20, 1000} {
+		minValue, maxValue := n/4, n-n/4
+		for _, tt := range []struct {
+			Foreach  func(*intrusive.RBTree) iterator
+			Min, Max int
+			Reverse  bool
+		}{
+			{
+				Foreach: func(rbt *intrusive.RBTree) iterator { return rbt.Foreach() },
+				Min:     0,
+				Max:     n,
+			},
+			{
+				Foreach: func(rbt *intrusive.RBTree) iterator { return rbt.ForeachReverse() },
+				Min:     0,
+				Max:     n,
+				Reverse: true,
+			},
+			{
+				Foreach: func(rbt *intrusive.RBTree) iterator {
+					return rbt.ForeachRange(minValue, maxValue, true, false)
+				},
+				Min: minValue,
+				Max: maxValue,
+			},
+			{
+				Foreach: func(rbt *intrusive.RBTree) iterator {
+					return rbt.ForeachRangeReverse(minValue, maxValue, true, false)
+				},
+				Min:     minValue,
+				Max:     maxValue,
+				Reverse: true,
+			},
+		} {
+			rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+			rs := make([]recordOfRBTree, n)
+			for j, v := range rand.Perm(n) {
+				r := &rs[j]
+				r.Value = v
+				rbt.InsertNode(&r.RBTreeNode)
+			}
+			var vs []int
+			for it := tt.Foreach(rbt); !it.IsAtEnd(); it.Advance() {
+				r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+				r.RBTreeNode = intrusive.RBTreeNode{}
+				if tt.Reverse {
+					vs = append(vs, n-1-r.Value)
+				} else {
+					vs = append(vs, r.Value)
+				}
+			}
+			assert.Len(t, vs, tt.Max-tt.Min, "case %d", i)
+			assert.True(t, sort.IntsAreSorted(vs), "case %d", i)
+			for j := range rs {
+				r := &rs[j]
+				assert.Equal(t, r.Value >= tt.Min && r.Value < tt.Max, r.RBTreeNode.IsReset(), "case %d", i)
+			}
+		}
+	}
+}
+
+func TestRBTreeOrderStatistics(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+	_, ok := rbt.GetNodeByRank(0)
+	assert.False(t, ok)
+	assert.Equal(t, 0, rbt.CountRange(0, 100, true, true))
+	var rs [1000]recordOfRBTree
+	for i := range rs {
+		rs[i].Value = i / 2
+	}
+	rand.Shuffle(len(rs), func(i, j int) {
+		rs[i].Value, rs[j].Value = rs[j].Value, rs[i].Value
+	})
+	for i := range rs {
+		rbt.InsertNode(&rs[i].RBTreeNode)
+	}
+	for i := 0; i < len(rs); i += 3 {
+		rbt.RemoveNode(&rs[i].RBTreeNode)
+	}
+	var vs []int
+	var rank int
+	for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+		r := (*recordOfRBTree)(it.Node().GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+		vs = append(vs, r.Value)
+		assert.Equal(t, rank, rbt.GetRank(it.Node()))
+		rbtn, ok := rbt.GetNodeByRank(rank)
+		if assert.True(t, ok) {
+			assert.Equal(t, it.Node(), rbtn)
+		}
+		rank++
+	}
+	assert.Equal(t, rbt.NumberOfNodes(), rank)
+	_, ok = rbt.GetNodeByRank(rank)
+	assert.False(t, ok)
+	_, ok = rbt.GetNodeByRank(-1)
+	assert.False(t, ok)
+	for _, tt := range []struct {
+		MinKey, MaxKey                       int
+		MinKeyIsInclusive, MaxKeyIsInclusive bool
+	}{
+		{-1, 1000, false, false},
+		{10, 20, false, false},
+		{10, 20, true, false},
+		{10, 20, false, true},
+		{10, 20, true, true},
+		{20, 10, true, true},
+		{33, 33, true, true},
+		{33, 33, false, true},
+		{498, 600, true, false},
+	} {
+		var n int
+		for _, v := range vs {
+			if (v > tt.MinKey || (v == tt.MinKey && tt.MinKeyIsInclusive)) &&
+				(v < tt.MaxKey || (v == tt.MaxKey && tt.MaxKeyIsInclusive)) {
+				n++
+			}
+		}
+		assert.Equal(t, n, rbt.CountRange(tt.MinKey, tt.MaxKey, tt.MinKeyIsInclusive, tt.MaxKeyIsInclusive), "%v", tt)
+	}
+}
+
+func TestRBTreeOrderStatisticsNotEnabled(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [10]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = i
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	assert.PanicsWithValue(t, "intrusive: order statistics of RBTree not enabled", func() { rbt.GetNodeByRank(0) })
+	assert.PanicsWithValue(t, "intrusive: order statistics of RBTree not enabled", func() { rbt.GetRank(&rs[9].RBTreeNode) })
+	assert.PanicsWithValue(t, "intrusive: order statistics of RBTree not enabled", func() { rbt.CountRange(0, 10, true, false) })
+	assert.PanicsWithValue(t, "intrusive: order statistics enabled for non-empty RBTree", func() { rbt.EnableOrderStatistics() })
+	rbt.Clear(nil)
+	rbt.EnableOrderStatistics()
+	assert.Equal(t, 0, rbt.CountRange(0, 10, true, false))
+}
+
+func TestRBTreeSetNodeAugmenter(t *testing.T) {
+	type Record struct {
+		RBTreeNode intrusive.RBTreeNode
+		Value      int
+		Sum        int
+	}
+	getRecord := func(rbtn *intrusive.RBTreeNode) *Record {
+		return (*Record)(rbtn.GetContainer(unsafe.Offsetof(Record{}.RBTreeNode)))
+	}
+	order := func(node1 *intrusive.RBTreeNode, node2 *intrusive.RBTreeNode) bool {
+		return getRecord(node1).Value < getRecord(node2).Value
+	}
+	compare := func(node *intrusive.RBTreeNode, value interface{}) int64 {
+		return int64(getRecord(node).Value - value.(int))
+	}
+	var n int
+	augment := func(node *intrusive.RBTreeNode, leftChild *intrusive.RBTreeNode, rightChild *intrusive.RBTreeNode) bool {
+		n++
+		r := getRecord(node)
+		sum := r.Value
+		if leftChild != nil {
+			sum += getRecord(leftChild).Sum
+		}
+		if rightChild != nil {
+			sum += getRecord(rightChild).Sum
+		}
+		if sum == r.Sum {
+			return false
+		}
+		r.Sum = sum
+		return true
+	}
+	rbt := new(intrusive.RBTree).Init(order, compare).SetNodeAugmenter(augment)
+	var check func(*intrusive.RBTreeNode) int
+	check = func(node *intrusive.RBTreeNode) int {
+		sum := getRecord(node).Value
+		if leftChild, ok := node.GetLeftChild(rbt); ok {
+			parent, ok := leftChild.GetParent(rbt)
+			assert.True(t, ok)
+			assert.Equal(t, node, parent)
+			sum += check(leftChild)
+		}
+		if rightChild, ok := node.GetRightChild(rbt); ok {
+			sum += check(rightChild)
+		}
+		assert.Equal(t, sum, getRecord(node).Sum)
+		return sum
+	}
+	var rs [1000]Record
+	for i := range rs {
+		rs[i].Value = i % 100
+	}
+	rand.Shuffle(len(rs), func(i, j int) {
+		rs[i].Value, rs[j].Value = rs[j].Value, rs[i].Value
+	})
+	var sum int
+	for i := range rs {
+		rbt.InsertNode(&rs[i].RBTreeNode)
+		sum += rs[i].Value
+	}
+	root, ok := rbt.GetRoot()
+	if assert.True(t, ok) {
+		_, ok = root.GetParent(rbt)
+		assert.False(t, ok)
+		assert.Equal(t, sum, check(root))
+	}
+	for i := 0; i < len(rs); i += 2 {
+		rbt.RemoveNode(&rs[i].RBTreeNode)
+		sum -= rs[i].Value
+		if root, ok := rbt.GetRoot(); assert.True(t, ok) && i%50 == 0 {
+			assert.Equal(t, sum, check(root))
+		}
+	}
+	root, ok = rbt.GetRoot()
+	if assert.True(t, ok) {
+		assert.Equal(t, sum, check(root))
+	}
+	assert.Greater(t, n, 0)
+	assert.PanicsWithValue(t, "intrusive: node augmenter set for non-empty RBTree", func() { rbt.SetNodeAugmenter(augment) })
+}
+
+func TestRBTreeBuildFromSorted(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 7, 8, 100, 1023, 1024, 1025, 10000} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+		rs := make([]recordOfRBTree, n)
+		rbtns := make([]*intrusive.RBTreeNode, n)
+		for i := range rs {
+			r := &rs[i]
+			r.Value = i
+			rbtns[i] = &r.RBTreeNode
+		}
+		rbt.BuildFromSorted(rbtns)
+		assert.Equal(t, n, rbt.NumberOfNodes(), "n=%d", n)
+		checkRBTreeDepth(t, rbt)
+		var i int
+		for it := rbt.Foreach(); !it.IsAtEnd(); it.Advance() {
+			assert.Equal(t, &rs[i].RBTreeNode, it.Node(), "n=%d", n)
+			assert.Equal(t, i, rbt.GetRank(it.Node()), "n=%d", n)
+			i++
+		}
+		assert.Equal(t, n, i, "n=%d", n)
+		for i := 0; i < n; i += 2 {
+			rbt.RemoveNode(&rs[i].RBTreeNode)
+		}
+		for i := 1; i < n; i += 2 {
+			rbtn, ok := rbt.FindNode(i)
+			if assert.True(t, ok, "n=%d", n) {
+				assert.Equal(t, &rs[i].RBTreeNode, rbtn, "n=%d", n)
+			}
+		}
+		assert.Equal(t, n/2, rbt.NumberOfNodes(), "n=%d", n)
+		checkRBTreeDepth(t, rbt)
+		assert.NoError(t, rbt.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "n=%d", n)
+		if n >= 2 {
+			r := recordOfRBTree{Value: -1}
+			assert.PanicsWithValue(t, "intrusive: sorted nodes built into non-empty RBTree", func() {
+				rbt.BuildFromSorted([]*intrusive.RBTreeNode{&r.RBTreeNode})
+			}, "n=%d", n)
+			assert.True(t, r.RBTreeNode.IsReset(), "n=%d", n)
+			assert.NoError(t, rbt.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "n=%d", n)
+		}
+	}
+}
+
+func TestRBTreeSplitAtJoin(t *testing.T) {
+	for i, tt := range []struct {
+		N                      int
+		Key                    int
+		OrderStatistics        bool
+		JoinReverse            bool
+		NodeCount1, NodeCount2 int
+	}{
+		{N: 0, Key: 0, NodeCount1: 0, NodeCount2: 0},
+		{N: 1, Key: 0, NodeCount1: 0, NodeCount2: 1},
+		{N: 1, Key: 1, NodeCount1: 1, NodeCount2: 0},
+		{N: 100, Key: 50, NodeCount1: 50, NodeCount2: 50},
+		{N: 100, Key: 50, JoinReverse: true, NodeCount1: 50, NodeCount2: 50},
+		{N: 100, Key: 50, OrderStatistics: true, NodeCount1: 50, NodeCount2: 50},
+		{N: 1000, Key: 3, NodeCount1: 3, NodeCount2: 997},
+		{N: 1000, Key: 997, OrderStatistics: true, JoinReverse: true, NodeCount1: 997, NodeCount2: 3},
+		{N: 1000, Key: -1, NodeCount1: 0, NodeCount2: 1000},
+		{N: 1000, Key: 1000, NodeCount1: 1000, NodeCount2: 0},
+		{N: 12345, Key: 6789, NodeCount1: 6789, NodeCount2: 5556},
+	} {
+		newRBTree := func() *intrusive.RBTree {
+			rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+			if tt.OrderStatistics {
+				rbt.EnableOrderStatistics()
+			}
+			return rbt
+		}
+		rbt1 := newRBTree()
+		rs := make([]recordOfRBTree, tt.N)
+		for _, j := range rand.Perm(tt.N) {
+			r := &rs[j]
+			r.Value = j
+			rbt1.InsertNode(&r.RBTreeNode)
+		}
+		rbt2 := newRBTree()
+		rbt1.SplitAt(tt.Key, rbt2)
+		assert.Equal(t, tt.NodeCount1, rbt1.NumberOfNodes(), "case %d", i)
+		assert.Equal(t, tt.NodeCount2, rbt2.NumberOfNodes(), "case %d", i)
+		checkRBTreeDepth(t, rbt1)
+		checkRBTreeDepth(t, rbt2)
+		assert.NoError(t, rbt1.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "case %d", i)
+		assert.NoError(t, rbt2.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "case %d", i)
+		for j := range rs {
+			rbt := rbt1
+			if j >= tt.Key {
+				rbt = rbt2
+			}
+			rbtn, ok := rbt.FindNode(j)
+			if assert.True(t, ok, "case %d", i) {
+				assert.Equal(t, &rs[j].RBTreeNode, rbtn, "case %d", i)
+				if tt.OrderStatistics {
+					rank := j
+					if rbt == rbt2 {
+						rank -= tt.NodeCount1
+					}
+					assert.Equal(t, rank, rbt.GetRank(rbtn), "case %d", i)
+				}
+			}
+		}
+		if tt.JoinReverse {
+			rbt2.Join(rbt1)
+			rbt1, rbt2 = rbt2, rbt1
+		} else {
+			rbt1.Join(rbt2)
+		}
+		assert.True(t, rbt2.IsEmpty(), "case %d", i)
+		assert.Equal(t, 0, rbt2.NumberOfNodes(), "case %d", i)
+		assert.Equal(t, tt.N, rbt1.NumberOfNodes(), "case %d", i)
+		checkRBTreeDepth(t, rbt1)
+		assert.NoError(t, rbt1.ValidateWithKeys(keyOfRBTreeNodeOfRecord), "case %d", i)
+		var j int
+		for it := rbt1.Foreach(); !it.IsAtEnd(); it.Advance() {
+			assert.Equal(t, &rs[j].RBTreeNode, it.Node(), "case %d", i)
+			if tt.OrderStatistics {
+				assert.Equal(t, j, rbt1.GetRank(it.Node()), "case %d", i)
+			}
+			j++
+		}
+		assert.Equal(t, tt.N, j, "case %d", i)
+		for j := range rs {
+			rbt1.RemoveNode(&rs[j].RBTreeNode)
+		}
+		assert.True(t, rbt1.IsEmpty(), "case %d", i)
+	}
+}
+
+func TestRBTreeSetOperations(t *testing.T) {
+	for i, tt := range []struct {
+		Op             string
+		In1, In2       []int
+		Out, Discarded string
+	}{
+		{Op: "union", In1: []int{}, In2: []int{}, Out: "", Discarded: ""},
+		{Op: "union", In1: []int{1, 3, 5}, In2: []int{}, Out: "1,3,5", Discarded: ""},
+		{Op: "union", In1: []int{}, In2: []int{2, 4}, Out: "2,4", Discarded: ""},
+		{Op: "union", In1: []int{1, 3, 5, 7}, In2: []int{2, 3, 6, 7, 8}, Out: "1,2,3,5,6,7,8", Discarded: "3,7"},
+		{Op: "union", In1: []int{1, 1, 2}, In2: []int{1, 2, 2, 3}, Out: "1,1,2,3", Discarded: "1,2,2"},
+		{Op: "intersect", In1: []int{1, 3, 5}, In2: []int{}, Out: "", Discarded: "1,3,5"},
+		{Op: "intersect", In1: []int{1, 3, 5, 7}, In2: []int{2, 3, 6, 7, 8}, Out: "3,7", Discarded: "1,2,3,5,6,7,8"},
+		{Op: "intersect", In1: []int{1, 1, 2, 4}, In2: []int{1, 3, 4, 4}, Out: "1,1,4", Discarded: "1,2,3,4,4"},
+		{Op: "difference", In1: []int{1, 3, 5}, In2: []int{}, Out: "1,3,5", Discarded: ""},
+		{Op: "difference", In1: []int{1, 3, 5, 7}, In2: []int{2, 3, 6, 7, 8}, Out: "1,5", Discarded: "2,3,3,6,7,7,8"},
+		{Op: "difference", In1: []int{1, 1, 2, 4}, In2: []int{1, 3}, Out: "2,4", Discarded: "1,1,1,3"},
+	} {
+		var rbts [2]intrusive.RBTree
+		for j, in := range [][]int{tt.In1, tt.In2} {
+			rbt := rbts[j].Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+			for _, v := range in {
+				rbt.InsertNode(&(&recordOfRBTree{Value: v}).RBTreeNode)
+			}
+		}
+		var values []string
+		onDiscarded := func(rbtn *intrusive.RBTreeNode) {
+			r := (*recordOfRBTree)(rbtn.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode)))
+			values = append(values, fmt.Sprint(r.Value))
+		}
+		switch tt.Op {
+		case "union":
+			rbts[0].Union(&rbts[1], onDiscarded)
+		case "intersect":
+			rbts[0].Intersect(&rbts[1], onDiscarded)
+		case "difference":
+			rbts[0].Difference(&rbts[1], onDiscarded)
+		}
+		assert.True(t, rbts[1].IsEmpty(), "case %d", i)
+		assert.Equal(t, 0, rbts[1].NumberOfNodes(), "case %d", i)
+		assert.Equal(t, len(tt.In1)+len(tt.In2)-len(values), rbts[0].NumberOfNodes(), "case %d", i)
+		checkRBTreeDepth(t, &rbts[0])
+		assert.NoError(t, rbts[0].ValidateWithKeys(keyOfRBTreeNodeOfRecord), "case %d", i)
+		var j int
+		for it := rbts[0].Foreach(); !it.IsAtEnd(); it.Advance() {
+			assert.Equal(t, j, rbts[0].GetRank(it.Node()), "case %d", i)
+			j++
+		}
+		assert.Equal(t, tt.Discarded, strings.Join(values, ","), "case %d", i)
+		assert.Equal(t, tt.Out, dumpRecordRBTree(&rbts[0]), "case %d", i)
+	}
+}
+
+func TestRBTreeClear(t *testing.T) {
+	for i, n := range []int{0, 1, 2, 1000} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+		rs := make([]recordOfRBTree, n)
+		for j := range rs {
+			rs[j].Value = j
+			rbt.InsertNode(&rs[j].RBTreeNode)
+		}
+		visited := make(map[*intrusive.RBTreeNode]bool)
+		rbt.Clear(func(rbtn *intrusive.RBTreeNode) {
+			assert.True(t, rbtn.IsReset(), "case %d", i)
+			assert.False(t, visited[rbtn], "case %d", i)
+			visited[rbtn] = true
+		})
+		assert.Len(t, visited, n, "case %d", i)
+		assert.True(t, rbt.IsEmpty(), "case %d", i)
+		assert.Equal(t, 0, rbt.NumberOfNodes(), "case %d", i)
+		_, ok := rbt.GetMin()
+		assert.False(t, ok, "case %d", i)
+		for j := range rs {
+			assert.True(t, rs[j].RBTreeNode.IsReset(), "case %d", i)
+		}
+		for _, j := range rand.Perm(n) {
+			rbt.InsertNode(&rs[j].RBTreeNode)
+		}
+		checkRBTreeDepth(t, rbt)
+		for j := range rs {
+			assert.Equal(t, j, rbt.GetRank(&rs[j].RBTreeNode), "case %d", i)
+		}
+	}
+}
+
 func TestRBTree(t *testing.T) {
 	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
 	var rs [100000]recordOfRBTree
@@ -232,9 +1177,12 @@ func TestRBTree(t *testing.T) {
 			removedRecordIndexes[j] = struct{}{}
 		}
 	}
+	assert.Equal(t, len(rs)-len(removedRecordIndexes), rbt.NumberOfNodes())
 	for j := range removedRecordIndexes {
 		rbt.InsertNode(&rs[j].RBTreeNode)
 	}
+	assert.NoError(t, rbt.ValidateWithKeys(keyOfRBTreeNodeOfRecord))
+	assert.Equal(t, len(rs), rbt.NumberOfNodes())
 	for i := range rs {
 		r := &rs[i]
 		rbtn, ok := rbt.FindNode(r.Value)
@@ -247,6 +1195,136 @@ func TestRBTree(t *testing.T) {
 		rbt.RemoveNode(&r.RBTreeNode)
 	}
 	assert.True(t, rbt.IsEmpty())
+	assert.Equal(t, 0, rbt.NumberOfNodes())
+}
+
+func TestRBTreeValidate(t *testing.T) {
+	// Inserting 1 to 6 in order shapes the tree as: 2 (1, 4 (3, 5 (nil, 6))),
+	// with 4 and 6 being red.
+	for i, tt := range []struct {
+		In            func([]recordOfRBTree)
+		NodeKeyGetter func(*intrusive.RBTreeNode) interface{}
+		Err           string
+	}{
+		{
+			In:            func(rs []recordOfRBTree) {},
+			NodeKeyGetter: keyOfRBTreeNodeOfRecord,
+			Err:           "",
+		},
+		{
+			In: func(rs []recordOfRBTree) {
+				rs[0].Value = 10
+			},
+			Err: "intrusive: rbtree node root: less than the previous node",
+		},
+		{
+			In: func(rs []recordOfRBTree) {
+				rs[2].Value = 0
+			},
+			Err: "intrusive: rbtree node root.R.L: less than the previous node",
+		},
+		{
+			In: func(rs []recordOfRBTree) {
+				*colorOfRBTreeNode(&rs[5].RBTreeNode) = *colorOfRBTreeNode(&rs[4].RBTreeNode)
+			},
+			Err: "intrusive: rbtree node root.R.R: black heights of subtrees differ: 0 (left) vs 1 (right)",
+		},
+		{
+			In: func(rs []recordOfRBTree) {
+				*colorOfRBTreeNode(&rs[2].RBTreeNode) = *colorOfRBTreeNode(&rs[3].RBTreeNode)
+			},
+			Err: "intrusive: rbtree node root.R.L: red node with red parent",
+		},
+		{
+			In: func(rs []recordOfRBTree) {},
+			NodeKeyGetter: func(rbtn *intrusive.RBTreeNode) interface{} {
+				return keyOfRBTreeNodeOfRecord(rbtn).(int) * 2
+			},
+			Err: "intrusive: rbtree node root.L: node comparer disagrees with node orderer on the node itself",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod).EnableOrderStatistics()
+		assert.NoError(t, rbt.Validate(), "case %d", i)
+		rs := make([]recordOfRBTree, 6)
+		for j := range rs {
+			rs[j].Value = j + 1
+			rbt.InsertNode(&rs[j].RBTreeNode)
+		}
+		tt.In(rs)
+		if tt.Err == "" {
+			assert.NoError(t, rbt.ValidateWithKeys(tt.NodeKeyGetter), "case %d", i)
+		} else {
+			assert.EqualError(t, rbt.ValidateWithKeys(tt.NodeKeyGetter), tt.Err, "case %d", i)
+		}
+	}
+}
+
+func TestRBTreeAllBackwardRange(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [10]recordOfRBTree
+	for _, i := range rand.Perm(len(rs)) {
+		rs[i].Value = i + 1
+		rbt.InsertNode(&rs[i].RBTreeNode)
+	}
+	for i, tt := range []struct {
+		Seq   func() iter.Seq[*intrusive.RBTreeNode]
+		Break int
+		Out   []int
+	}{
+		{Seq: rbt.All, Out: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{Seq: rbt.All, Break: 2, Out: []int{1, 2}},
+		{Seq: rbt.Backward, Out: []int{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}},
+		{Seq: rbt.Backward, Break: 1, Out: []int{10}},
+		{Seq: func() iter.Seq[*intrusive.RBTreeNode] { return rbt.Range(3, 7) }, Out: []int{3, 4, 5, 6}},
+		{Seq: func() iter.Seq[*intrusive.RBTreeNode] { return rbt.Range(0, 100) }, Out: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{Seq: func() iter.Seq[*intrusive.RBTreeNode] { return rbt.Range(5, 5) }, Out: nil},
+		{Seq: func() iter.Seq[*intrusive.RBTreeNode] { return rbt.Range(7, 3) }, Out: nil},
+	} {
+		var vs []int
+		for rbtn := range tt.Seq() {
+			if vs = append(vs, keyOfRBTreeNodeOfRecord(rbtn).(int)); len(vs) == tt.Break {
+				break
+			}
+		}
+		assert.Equal(t, tt.Out, vs, "case %d", i)
+	}
+	var vs []int
+	for rbtn := range rbt.Range(3, 8) {
+		if v := keyOfRBTreeNodeOfRecord(rbtn).(int); v%2 == 1 {
+			rbt.RemoveNode(rbtn)
+			vs = append(vs, v)
+		}
+	}
+	assert.Equal(t, []int{3, 5, 7}, vs)
+	assert.NoError(t, rbt.ValidateWithKeys(keyOfRBTreeNodeOfRecord))
+	vs = nil
+	for rbtn := range rbt.Backward() {
+		rbt.RemoveNode(rbtn)
+		vs = append(vs, keyOfRBTreeNodeOfRecord(rbtn).(int))
+	}
+	assert.Equal(t, []int{10, 9, 8, 6, 4, 2, 1}, vs)
+	assert.True(t, rbt.IsEmpty())
+}
+
+func TestRBTreeAllAllocs(t *testing.T) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [100]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = i + 1
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	var n int
+	allocs := testing.AllocsPerRun(10, func() {
+		for range rbt.All() {
+			n++
+		}
+		for range rbt.Backward() {
+			n++
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, 11*2*len(rs), n)
 }
 
 type recordOfRBTree struct {
@@ -263,6 +1341,10 @@ func compareRBTreeNodeOfRecrod(node *intrusive.RBTreeNode, value interface{}) in
 	return int64((*recordOfRBTree)(node.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode))).Value - value.(int))
 }
 
+func keyOfRBTreeNodeOfRecord(node *intrusive.RBTreeNode) interface{} {
+	return (*recordOfRBTree)(node.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode))).Value
+}
+
 func dumpRecordRBTree(rbTree *intrusive.RBTree) string {
 	var buffer bytes.Buffer
 
@@ -296,3 +1378,46 @@ func dumpReverseRecordRBTree(rbTree *intrusive.RBTree) string {
 
 	return ""
 }
+
+func checkRBTreeDepth(t *testing.T, rbTree *intrusive.RBTree) {
+	var getDepth func(*intrusive.RBTreeNode) int
+	getDepth = func(rbTreeNode *intrusive.RBTreeNode) int {
+		depth := 0
+
+		if leftChild, ok := rbTreeNode.GetLeftChild(rbTree); ok {
+			if parent, _ := leftChild.GetParent(rbTree); !assert.Equal(t, rbTreeNode, parent) {
+				return 0
+			}
+
+			depth = getDepth(leftChild)
+		}
+
+		if rightChild, ok := rbTreeNode.GetRightChild(rbTree); ok {
+			if parent, _ := rightChild.GetParent(rbTree); !assert.Equal(t, rbTreeNode, parent) {
+				return 0
+			}
+
+			if depth2 := getDepth(rightChild); depth2 > depth {
+				depth = depth2
+			}
+		}
+
+		return depth + 1
+	}
+
+	root, ok := rbTree.GetRoot()
+
+	if !ok {
+		return
+	}
+
+	depth := getDepth(root)
+	assert.LessOrEqual(t, float64(depth), 2*math.Log2(float64(rbTree.NumberOfNodes()+1)))
+}
+
+// colorOfRBTreeNode returns the color of the given node, which is the
+// second last field of RBTreeNode, for corrupting red-black trees.
+func colorOfRBTreeNode(rbTreeNode *intrusive.RBTreeNode) *int32 {
+	offset := unsafe.Sizeof(*rbTreeNode) - 2*unsafe.Sizeof(int32(0))
+	return (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(rbTreeNode)) + offset))
+}
@@ -0,0 +1,150 @@
+package intrusive
+
+// RBTreeNodeKeyComparer is the type of a function comparing the given node
+// with the given key of type K, as RBTreeNodeComparer does, without boxing
+// the key into an interface{}.
+type RBTreeNodeKeyComparer[K any] func(rbtn *RBTreeNode, key K) int64
+
+// FindRBTreeNodeFunc finds a node with the given key in the given tree, as
+// *RBTree.FindNode does, but compares nodes with the key by the given
+// comparer, which must agree with the node orderer of the tree.
+// As the key isn't boxed into an interface{}, it doesn't allocate.
+func FindRBTreeNodeFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	return findRBTreeNode(rbt, key, nodeKeyComparer)
+}
+
+// FindRBTreeLowerBoundFunc finds the first node with a key not less than
+// the given key in the given tree, as *RBTree.FindLowerBound does, but
+// compares nodes with the key by the given comparer.
+func FindRBTreeLowerBoundFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	return rbt.checkNode(findFirstRBTreeNode(rbt, key, true, nodeKeyComparer))
+}
+
+// FindRBTreeUpperBoundFunc finds the first node with a key greater than
+// the given key in the given tree, as *RBTree.FindUpperBound does, but
+// compares nodes with the key by the given comparer.
+func FindRBTreeUpperBoundFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	return rbt.checkNode(findFirstRBTreeNode(rbt, key, false, nodeKeyComparer))
+}
+
+// FindRBTreeFloorFunc finds the last node with a key not greater than the
+// given key in the given tree, as *RBTree.FindFloor does, but compares
+// nodes with the key by the given comparer.
+func FindRBTreeFloorFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	return rbt.checkNode(findLastRBTreeNode(rbt, key, true, nodeKeyComparer))
+}
+
+// FindRBTreeCeilingFunc finds the first node with a key not less than the
+// given key in the given tree, as *RBTree.FindCeiling does, but compares
+// nodes with the key by the given comparer.
+func FindRBTreeCeilingFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	return FindRBTreeLowerBoundFunc(rbt, key, nodeKeyComparer)
+}
+
+// InsertRBTreeNodeUniqueFunc inserts the given node with the given key to
+// the given tree unless a node with an identical key exists, as
+// *RBTree.InsertNodeUnique does, but compares nodes with the key by the
+// given comparer.
+func InsertRBTreeNodeUniqueFunc[K any](rbt *RBTree, x *RBTreeNode, key K, nodeKeyComparer RBTreeNodeKeyComparer[K]) (*RBTreeNode, bool) {
+	y, f, ok := findRBTreeNodeOrSlot(rbt, key, nodeKeyComparer)
+
+	if ok {
+		return y, false
+	}
+
+	rbt.insertNode(x, y, f)
+	return x, true
+}
+
+// FindOrInsertRBTreeNodeFunc finds a node with the given key in the given
+// tree, or otherwise inserts the node created by the given factory, as
+// *RBTree.FindOrInsertNode does, but compares nodes with the key by the
+// given comparer.
+func FindOrInsertRBTreeNodeFunc[K any](rbt *RBTree, key K, nodeKeyComparer RBTreeNodeKeyComparer[K], nodeFactory func() *RBTreeNode) (*RBTreeNode, bool) {
+	y, f, ok := findRBTreeNodeOrSlot(rbt, key, nodeKeyComparer)
+
+	if ok {
+		return y, false
+	}
+
+	x := nodeFactory()
+	rbt.insertNode(x, y, f)
+	return x, true
+}
+
+func findRBTreeNode[K any](rbt *RBTree, key K, nodeKeyComparer func(*RBTreeNode, K) int64) (*RBTreeNode, bool) {
+	x := rbt.root()
+
+	for !x.isNull(rbt) {
+		d := -nodeKeyComparer(x, key)
+
+		if d == 0 {
+			return x, true
+		}
+
+		if d < 0 {
+			x = x.leftChild
+		} else {
+			x = x.rightChild
+		}
+	}
+
+	return nil, false
+}
+
+func findFirstRBTreeNode[K any](rbt *RBTree, key K, keyIsInclusive bool, nodeKeyComparer func(*RBTreeNode, K) int64) *RBTreeNode {
+	x := rbt.root()
+	y := &rbt.header
+
+	for !x.isNull(rbt) {
+		if d := nodeKeyComparer(x, key); d > 0 || (d == 0 && keyIsInclusive) {
+			y = x
+			x = x.leftChild
+		} else {
+			x = x.rightChild
+		}
+	}
+
+	return y
+}
+
+func findLastRBTreeNode[K any](rbt *RBTree, key K, keyIsInclusive bool, nodeKeyComparer func(*RBTreeNode, K) int64) *RBTreeNode {
+	x := rbt.root()
+	y := &rbt.header
+
+	for !x.isNull(rbt) {
+		if d := nodeKeyComparer(x, key); d < 0 || (d == 0 && keyIsInclusive) {
+			y = x
+			x = x.rightChild
+		} else {
+			x = x.leftChild
+		}
+	}
+
+	return y
+}
+
+// findRBTreeNodeOrSlot finds a node with the given key in the given tree.
+// If no such node exists, it returns the parent and the child setter of
+// the slot for a node with the given key instead.
+func findRBTreeNodeOrSlot[K any](rbt *RBTree, key K, nodeKeyComparer func(*RBTreeNode, K) int64) (*RBTreeNode, func(*RBTreeNode, *RBTreeNode), bool) {
+	y := &rbt.header
+	z, f := y.leftChild /* rbt.root() */, (*RBTreeNode).setLeftChild /* rbt.setRoot() */
+
+	for !z.isNull(rbt) {
+		y = z
+		d := nodeKeyComparer(y, key)
+
+		if d == 0 {
+			return y, nil, true
+		}
+
+		if d > 0 {
+			z, f = y.leftChild, (*RBTreeNode).setLeftChild
+		} else {
+			z, f = y.rightChild, (*RBTreeNode).setRightChild
+		}
+	}
+
+	return y, f, false
+}
@@ -0,0 +1,316 @@
+package intrusive_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRBTreeNodeFunc(t *testing.T) {
+	for i, tt := range []struct {
+		In []int
+	}{
+		{
+			In: []int{},
+		},
+		{
+			In: []int{1, 2, 3, 4, 5, 6},
+		},
+		{
+			In: []int{-100, 0, 1, 2, 3, 4, 5, 6, 7, 100},
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		rs := [...]recordOfRBTree{{Value: 1}, {Value: 2}, {Value: 2}, {Value: 2}, {Value: 4}, {Value: 5}, {Value: 6}}
+		for i := range rs {
+			rbt.InsertNode(&rs[i].RBTreeNode)
+		}
+		for _, v := range tt.In {
+			rbtn, ok := rbt.FindNode(v)
+			rbtn2, ok2 := intrusive.FindRBTreeNodeFunc(rbt, v, compareRBTreeNodeOfRecordWithInt)
+			assert.Equal(t, ok, ok2, "case %d", i)
+			assert.Equal(t, rbtn, rbtn2, "case %d", i)
+			for j, fs := range [...][2]func() (*intrusive.RBTreeNode, bool){
+				{
+					func() (*intrusive.RBTreeNode, bool) { return rbt.FindLowerBound(v) },
+					func() (*intrusive.RBTreeNode, bool) {
+						return intrusive.FindRBTreeLowerBoundFunc(rbt, v, compareRBTreeNodeOfRecordWithInt)
+					},
+				},
+				{
+					func() (*intrusive.RBTreeNode, bool) { return rbt.FindUpperBound(v) },
+					func() (*intrusive.RBTreeNode, bool) {
+						return intrusive.FindRBTreeUpperBoundFunc(rbt, v, compareRBTreeNodeOfRecordWithInt)
+					},
+				},
+				{
+					func() (*intrusive.RBTreeNode, bool) { return rbt.FindFloor(v) },
+					func() (*intrusive.RBTreeNode, bool) {
+						return intrusive.FindRBTreeFloorFunc(rbt, v, compareRBTreeNodeOfRecordWithInt)
+					},
+				},
+				{
+					func() (*intrusive.RBTreeNode, bool) { return rbt.FindCeiling(v) },
+					func() (*intrusive.RBTreeNode, bool) {
+						return intrusive.FindRBTreeCeilingFunc(rbt, v, compareRBTreeNodeOfRecordWithInt)
+					},
+				},
+			} {
+				rbtn, ok := fs[0]()
+				rbtn2, ok2 := fs[1]()
+				assert.Equal(t, ok, ok2, "case %d, %d, %d", i, v, j)
+				assert.Equal(t, rbtn, rbtn2, "case %d, %d, %d", i, v, j)
+			}
+		}
+	}
+}
+
+func TestInsertRBTreeNodeUniqueFunc(t *testing.T) {
+	for i, tt := range []struct {
+		In  []int
+		Out string
+	}{
+		{
+			In:  []int{},
+			Out: "",
+		},
+		{
+			In:  []int{3, 1, 2, 1, 3, 3},
+			Out: "1,2,3",
+		},
+		{
+			In:  []int{6, 5, 4, 3, 2, 1, 6, 5, 4, 3, 2, 1},
+			Out: "1,2,3,4,5,6",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		rs := make(map[int]*recordOfRBTree)
+		for _, v := range tt.In {
+			r := &recordOfRBTree{Value: v}
+			rbtn, ok := intrusive.InsertRBTreeNodeUniqueFunc(rbt, &r.RBTreeNode, v, compareRBTreeNodeOfRecordWithInt)
+			if r2, ok2 := rs[v]; ok2 {
+				assert.False(t, ok, "case %d", i)
+				assert.Equal(t, &r2.RBTreeNode, rbtn, "case %d", i)
+			} else {
+				assert.True(t, ok, "case %d", i)
+				assert.Equal(t, &r.RBTreeNode, rbtn, "case %d", i)
+				rs[v] = r
+			}
+		}
+		assert.Equal(t, len(rs), rbt.NumberOfNodes(), "case %d", i)
+		assert.NoError(t, rbt.Validate(), "case %d", i)
+		assert.Equal(t, tt.Out, dumpRecordRBTree(rbt), "case %d", i)
+	}
+}
+
+func TestFindOrInsertRBTreeNodeFunc(t *testing.T) {
+	for i, tt := range []struct {
+		In  []int
+		Out string
+	}{
+		{
+			In:  []int{},
+			Out: "",
+		},
+		{
+			In:  []int{3, 1, 2, 1, 3, 3},
+			Out: "3,2,1",
+		},
+		{
+			In:  []int{1, 2, 3, 4, 5, 6, 6, 5, 4, 3, 2, 1},
+			Out: "6,5,4,3,2,1",
+		},
+	} {
+		rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+		var n int
+		for _, v := range tt.In {
+			rbtn, ok := intrusive.FindOrInsertRBTreeNodeFunc(rbt, v, compareRBTreeNodeOfRecordWithInt, func() *intrusive.RBTreeNode {
+				n++
+				return &(&recordOfRBTree{Value: v}).RBTreeNode
+			})
+			rbtn2, _ := rbt.FindNode(v)
+			assert.Equal(t, rbtn2, rbtn, "case %d", i)
+			if ok {
+				assert.Equal(t, n, rbt.NumberOfNodes(), "case %d", i)
+			}
+		}
+		assert.Equal(t, n, rbt.NumberOfNodes(), "case %d", i)
+		assert.NoError(t, rbt.Validate(), "case %d", i)
+		assert.Equal(t, tt.Out, dumpReverseRecordRBTree(rbt), "case %d", i)
+	}
+}
+
+func TestRBTreeFuncAllocs(t *testing.T) {
+	rbt1 := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs1 [100]recordOfRBTree
+	for i := range rs1 {
+		r := &rs1[i]
+		r.Value = (i + 1) * 1000
+		rbt1.InsertNode(&r.RBTreeNode)
+	}
+	rbt2 := new(intrusive.RBTree).Init(orderRBTreeNodeOfStringRecord, compareRBTreeNodeOfStringRecord)
+	rs2 := makeStringRecordsOfRBTree(100)
+	for i := range rs2 {
+		rbt2.InsertNode(&rs2[i].RBTreeNode)
+	}
+	var r1 recordOfRBTree
+	var r2 recordOfStringRBTree
+	var n int
+	allocs := testing.AllocsPerRun(10, func() {
+		for i := range rs1 {
+			v := rs1[i].Value
+			if _, ok := intrusive.FindRBTreeNodeFunc(rbt1, v, compareRBTreeNodeOfRecordWithInt); ok {
+				n++
+			}
+			if _, ok := intrusive.FindRBTreeLowerBoundFunc(rbt1, v-1, compareRBTreeNodeOfRecordWithInt); ok {
+				n++
+			}
+			if _, ok := intrusive.FindRBTreeFloorFunc(rbt1, v+1, compareRBTreeNodeOfRecordWithInt); ok {
+				n++
+			}
+			r1.Value = v
+			if _, ok := intrusive.InsertRBTreeNodeUniqueFunc(rbt1, &r1.RBTreeNode, v, compareRBTreeNodeOfRecordWithInt); !ok {
+				n++
+			}
+		}
+		for i := range rs2 {
+			v := rs2[i].Value
+			if _, ok := intrusive.FindRBTreeNodeFunc(rbt2, v, compareRBTreeNodeOfStringRecordWithString); ok {
+				n++
+			}
+			if _, ok := intrusive.FindRBTreeUpperBoundFunc(rbt2, v, compareRBTreeNodeOfStringRecordWithString); ok {
+				n++
+			}
+			r2.Value = v
+			if _, ok := intrusive.InsertRBTreeNodeUniqueFunc(rbt2, &r2.RBTreeNode, v, compareRBTreeNodeOfStringRecordWithString); !ok {
+				n++
+			}
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, 11*(4*len(rs1)+2*len(rs2)+len(rs2)-1), n)
+}
+
+func BenchmarkRBTreeFindNode(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [1000]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = (i + 1) * 1000
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rbt.FindNode(rs[i%len(rs)].Value)
+	}
+}
+
+func BenchmarkFindRBTreeNodeFunc(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [1000]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = (i + 1) * 1000
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intrusive.FindRBTreeNodeFunc(rbt, rs[i%len(rs)].Value, compareRBTreeNodeOfRecordWithInt)
+	}
+}
+
+func BenchmarkRBTreeFindNodeWithStringKey(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfStringRecord, compareRBTreeNodeOfStringRecord)
+	rs := makeStringRecordsOfRBTree(1000)
+	for i := range rs {
+		rbt.InsertNode(&rs[i].RBTreeNode)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rbt.FindNode(rs[i%len(rs)].Value)
+	}
+}
+
+func BenchmarkFindRBTreeNodeFuncWithStringKey(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfStringRecord, compareRBTreeNodeOfStringRecord)
+	rs := makeStringRecordsOfRBTree(1000)
+	for i := range rs {
+		rbt.InsertNode(&rs[i].RBTreeNode)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intrusive.FindRBTreeNodeFunc(rbt, rs[i%len(rs)].Value, compareRBTreeNodeOfStringRecordWithString)
+	}
+}
+
+func BenchmarkRBTreeInsertNodeUnique(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [1000]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = (i + 1) * 1000
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	var r recordOfRBTree
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Value = rs[i%len(rs)].Value
+		rbt.InsertNodeUnique(&r.RBTreeNode, r.Value)
+	}
+}
+
+func BenchmarkInsertRBTreeNodeUniqueFunc(b *testing.B) {
+	rbt := new(intrusive.RBTree).Init(orderRBTreeNodeOfRecord, compareRBTreeNodeOfRecrod)
+	var rs [1000]recordOfRBTree
+	for i := range rs {
+		r := &rs[i]
+		r.Value = (i + 1) * 1000
+		rbt.InsertNode(&r.RBTreeNode)
+	}
+	var r recordOfRBTree
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Value = rs[i%len(rs)].Value
+		intrusive.InsertRBTreeNodeUniqueFunc(rbt, &r.RBTreeNode, r.Value, compareRBTreeNodeOfRecordWithInt)
+	}
+}
+
+type recordOfStringRBTree struct {
+	Value      string
+	RBTreeNode intrusive.RBTreeNode
+}
+
+func makeStringRecordsOfRBTree(n int) []recordOfStringRBTree {
+	rs := make([]recordOfStringRBTree, n)
+	for i := range rs {
+		rs[i].Value = "key-" + strconv.Itoa(i)
+	}
+	return rs
+}
+
+func compareRBTreeNodeOfRecordWithInt(node *intrusive.RBTreeNode, value int) int64 {
+	return int64((*recordOfRBTree)(node.GetContainer(unsafe.Offsetof(recordOfRBTree{}.RBTreeNode))).Value - value)
+}
+
+func orderRBTreeNodeOfStringRecord(node1 *intrusive.RBTreeNode, node2 *intrusive.RBTreeNode) bool {
+	return (*recordOfStringRBTree)(node1.GetContainer(unsafe.Offsetof(recordOfStringRBTree{}.RBTreeNode))).Value <
+		(*recordOfStringRBTree)(node2.GetContainer(unsafe.Offsetof(recordOfStringRBTree{}.RBTreeNode))).Value
+}
+
+func compareRBTreeNodeOfStringRecord(node *intrusive.RBTreeNode, value interface{}) int64 {
+	return compareRBTreeNodeOfStringRecordWithString(node, value.(string))
+}
+
+func compareRBTreeNodeOfStringRecordWithString(node *intrusive.RBTreeNode, value string) int64 {
+	return int64(strings.Compare((*recordOfStringRBTree)(node.GetContainer(unsafe.Offsetof(recordOfStringRBTree{}.RBTreeNode))).Value, value))
+}
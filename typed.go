@@ -0,0 +1,28 @@
+package intrusive
+
+import "unsafe"
+
+// offsetOfNode returns the offset of the node field in elements, which
+// the given accessor returns for an element.
+// It panics if the accessor doesn't return a field of the element.
+func offsetOfNode[T any, N any](nodeAccessor func(*T) *N) uintptr {
+	x := new(T)
+	offset := uintptr(unsafe.Pointer(nodeAccessor(x))) - uintptr(unsafe.Pointer(x))
+
+	if elementSize := unsafe.Sizeof(*x); offset > elementSize || elementSize-offset < unsafe.Sizeof(*new(N)) {
+		panic("intrusive: node accessor not returning a field of the element")
+	}
+
+	return offset
+}
+
+// elementOfNode returns the element containing the given node at the given
+// offset.
+func elementOfNode[T any, N any](node *N, nodeOffset uintptr) *T {
+	return (*T)(unsafe.Add(unsafe.Pointer(node), -int(nodeOffset)))
+}
+
+// nodeOfElement returns the node of the given element at the given offset.
+func nodeOfElement[N any, T any](x *T, nodeOffset uintptr) *N {
+	return (*N)(unsafe.Add(unsafe.Pointer(x), nodeOffset))
+}
@@ -0,0 +1,114 @@
+package intrusive
+
+import "iter"
+
+// TypedHashMap presents a hash map of elements of type T, each of which
+// contains a HashMapNode field, so that elements are used directly instead
+// of nodes, as well as by the matcher.
+type TypedHashMap[T any] struct {
+	hm         HashMap
+	nodeOffset uintptr
+}
+
+// Init initializes the map with the given accessor, which returns the
+// HashMapNode field of an element, the given maximum load factor, the
+// given key hasher and the given matcher, which indicates whether the key
+// of the given element is equal to the given key, and then returns the map.
+func (thm *TypedHashMap[T]) Init(nodeAccessor func(*T) *HashMapNode, maxLoadFactor float64, keyHasher HashMapKeyHasher, matcher func(x *T, key interface{}) bool) *TypedHashMap[T] {
+	thm.nodeOffset = offsetOfNode(nodeAccessor)
+
+	thm.hm.Init(maxLoadFactor, keyHasher, func(hmn *HashMapNode, key interface{}) bool {
+		return matcher(thm.elementOf(hmn), key)
+	})
+
+	return thm
+}
+
+// Insert inserts the given element with the given key to the map.
+// The given key must be the key of the given element.
+func (thm *TypedHashMap[T]) Insert(x *T, key interface{}) {
+	thm.hm.InsertNode(thm.nodeOf(x), key)
+}
+
+// Remove removes the given element from the map and then resets the
+// HashMapNode field of the element.
+func (thm *TypedHashMap[T]) Remove(x *T) {
+	thm.hm.RemoveNode(thm.nodeOf(x))
+}
+
+// Find finds an element with the given key in the map and then returns
+// the element.
+// If no such element exists, it returns false.
+func (thm *TypedHashMap[T]) Find(key interface{}) (*T, bool) {
+	return thm.checkNode(thm.hm.FindNode(key))
+}
+
+// Clear removes all elements from the map in O(n) time, resets the
+// HashMapNode fields of the elements and calls the given callback, if any,
+// with every element.
+func (thm *TypedHashMap[T]) Clear(onElement func(*T)) {
+	if onElement == nil {
+		thm.hm.Clear(nil)
+		return
+	}
+
+	thm.hm.Clear(func(hmn *HashMapNode) {
+		onElement(thm.elementOf(hmn))
+	})
+}
+
+// All returns an iterator, for use with range-over-func, over all
+// elements in the map.
+// It's safe to remove the yielded element during the iteration.
+func (thm *TypedHashMap[T]) All() iter.Seq[*T] {
+	return thm.elements(thm.hm.All())
+}
+
+// AllMatching returns an iterator, for use with range-over-func, over all
+// elements with the given key in the map.
+// It's safe to remove the yielded element during the iteration.
+func (thm *TypedHashMap[T]) AllMatching(key interface{}) iter.Seq[*T] {
+	return thm.elements(thm.hm.AllMatching(key))
+}
+
+// IsEmpty indicates whether the map is empty.
+func (thm *TypedHashMap[T]) IsEmpty() bool {
+	return thm.hm.IsEmpty()
+}
+
+// NumberOfElements returns the number of elements in the map.
+func (thm *TypedHashMap[T]) NumberOfElements() int {
+	return thm.hm.NumberOfNodes()
+}
+
+// HashMap returns the underlying map of the HashMapNode fields of elements,
+// for the operations not covered by TypedHashMap.
+func (thm *TypedHashMap[T]) HashMap() *HashMap {
+	return &thm.hm
+}
+
+func (thm *TypedHashMap[T]) elements(nodes iter.Seq[*HashMapNode]) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		for hmn := range nodes {
+			if !yield(thm.elementOf(hmn)) {
+				return
+			}
+		}
+	}
+}
+
+func (thm *TypedHashMap[T]) checkNode(hmn *HashMapNode, ok bool) (*T, bool) {
+	if !ok {
+		return nil, false
+	}
+
+	return thm.elementOf(hmn), true
+}
+
+func (thm *TypedHashMap[T]) elementOf(hmn *HashMapNode) *T {
+	return elementOfNode[T](hmn, thm.nodeOffset)
+}
+
+func (thm *TypedHashMap[T]) nodeOf(x *T) *HashMapNode {
+	return nodeOfElement[HashMapNode](x, thm.nodeOffset)
+}
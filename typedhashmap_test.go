@@ -0,0 +1,56 @@
+package intrusive_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedHashMap(t *testing.T) {
+	thm := new(intrusive.TypedHashMap[recordOfHashMap]).Init(func(r *recordOfHashMap) *intrusive.HashMapNode {
+		return &r.HashMapNode
+	}, 0, hashKey, func(r *recordOfHashMap, key interface{}) bool {
+		return r.Value == key.(int)
+	})
+	_, ok := thm.Find(1)
+	assert.False(t, ok)
+	rs := make([]recordOfHashMap, 100)
+	for i := range rs {
+		rs[i].Value = i % 50
+		thm.Insert(&rs[i], i%50)
+	}
+	assert.Equal(t, len(rs), thm.NumberOfElements())
+	r, ok := thm.Find(7)
+	if assert.True(t, ok) {
+		assert.Equal(t, 7, r.Value)
+	}
+	n := 0
+	for r := range thm.AllMatching(7) {
+		assert.Equal(t, 7, r.Value)
+		thm.Remove(r)
+		assert.True(t, r.HashMapNode.IsReset())
+		n++
+	}
+	assert.Equal(t, 2, n)
+	_, ok = thm.Find(7)
+	assert.False(t, ok)
+	var vs []int
+	for r := range thm.All() {
+		if r.Value >= 10 {
+			thm.Remove(r)
+		} else {
+			vs = append(vs, r.Value)
+		}
+	}
+	sort.Ints(vs)
+	assert.Equal(t, []int{0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 8, 8, 9, 9}, vs)
+	assert.NoError(t, thm.HashMap().Validate())
+	n = 0
+	thm.Clear(func(*recordOfHashMap) {
+		n++
+	})
+	assert.Equal(t, 18, n)
+	assert.True(t, thm.IsEmpty())
+}
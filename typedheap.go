@@ -0,0 +1,135 @@
+package intrusive
+
+import "iter"
+
+// TypedHeap presents a binary heap of elements of type T, each of which
+// contains a HeapNode field, so that elements are used directly instead
+// of nodes, as well as by the orderer.
+type TypedHeap[T any] struct {
+	h          Heap
+	nodeOffset uintptr
+}
+
+// Init initializes the heap with the given accessor, which returns the
+// HeapNode field of an element, the given orderer, which indicates whether
+// the given element 1 is not greater than the given element 2, and the
+// given initial capacity, and then returns the heap.
+func (th *TypedHeap[T]) Init(nodeAccessor func(*T) *HeapNode, orderer func(x1 *T, x2 *T) bool, initialCapacity int) *TypedHeap[T] {
+	th.nodeOffset = offsetOfNode(nodeAccessor)
+
+	th.h.Init(func(hn1 *HeapNode, hn2 *HeapNode) bool {
+		return orderer(th.elementOf(hn1), th.elementOf(hn2))
+	}, initialCapacity)
+
+	return th
+}
+
+// Insert inserts the given element to the heap.
+func (th *TypedHeap[T]) Insert(x *T) {
+	th.h.InsertNode(th.nodeOf(x))
+}
+
+// Remove removes the given element from the heap and then resets the
+// HeapNode field of the element.
+func (th *TypedHeap[T]) Remove(x *T) {
+	th.h.RemoveNode(th.nodeOf(x))
+}
+
+// Fix restores the order of the heap after the key of the given element
+// has changed.
+// The given element must be in the heap.
+func (th *TypedHeap[T]) Fix(x *T) {
+	th.h.FixNode(th.nodeOf(x))
+}
+
+// PopTop removes the element with the minimum key from the heap and then
+// returns the element.
+// If the heap is empty, it returns false.
+func (th *TypedHeap[T]) PopTop() (*T, bool) {
+	return th.checkNode(th.h.PopTop())
+}
+
+// PushPop inserts the given element to the heap and then removes the
+// element with the minimum key from the heap, returning the removed
+// element, as *Heap.PushPop does.
+func (th *TypedHeap[T]) PushPop(x *T) *T {
+	return th.elementOf(th.h.PushPop(th.nodeOf(x)))
+}
+
+// ReplaceTop removes the element with the minimum key from the heap and
+// then inserts the given element to the heap, returning the removed
+// element, as *Heap.ReplaceTop does.
+// If the heap is empty, it just inserts the given element and returns
+// false.
+func (th *TypedHeap[T]) ReplaceTop(x *T) (*T, bool) {
+	return th.checkNode(th.h.ReplaceTop(th.nodeOf(x)))
+}
+
+// Clear removes all elements from the heap in O(n) time, resets the
+// HeapNode fields of the elements and calls the given callback, if any,
+// with every element.
+func (th *TypedHeap[T]) Clear(onElement func(*T)) {
+	if onElement == nil {
+		th.h.Clear(nil)
+		return
+	}
+
+	th.h.Clear(func(hn *HeapNode) {
+		onElement(th.elementOf(hn))
+	})
+}
+
+// GetTop returns the element with the minimum key in the heap.
+// If the heap is empty, it returns false.
+func (th *TypedHeap[T]) GetTop() (*T, bool) {
+	return th.checkNode(th.h.GetTop())
+}
+
+// All returns an iterator, for use with range-over-func, over all
+// elements in the heap in no particular order.
+// It's safe to remove the yielded element during the iteration.
+func (th *TypedHeap[T]) All() iter.Seq[*T] {
+	return th.elements(th.h.All())
+}
+
+// IsEmpty indicates whether the heap is empty.
+func (th *TypedHeap[T]) IsEmpty() bool {
+	return th.h.IsEmpty()
+}
+
+// NumberOfElements returns the number of elements in the heap.
+func (th *TypedHeap[T]) NumberOfElements() int {
+	return th.h.NumberOfNodes()
+}
+
+// Heap returns the underlying heap of the HeapNode fields of elements,
+// for the operations not covered by TypedHeap.
+func (th *TypedHeap[T]) Heap() *Heap {
+	return &th.h
+}
+
+func (th *TypedHeap[T]) elements(nodes iter.Seq[*HeapNode]) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		for hn := range nodes {
+			if !yield(th.elementOf(hn)) {
+				return
+			}
+		}
+	}
+}
+
+func (th *TypedHeap[T]) checkNode(hn *HeapNode, ok bool) (*T, bool) {
+	if !ok {
+		return nil, false
+	}
+
+	return th.elementOf(hn), true
+}
+
+func (th *TypedHeap[T]) elementOf(hn *HeapNode) *T {
+	return elementOfNode[T](hn, th.nodeOffset)
+}
+
+func (th *TypedHeap[T]) nodeOf(x *T) *HeapNode {
+	return nodeOfElement[HeapNode](x, th.nodeOffset)
+}
@@ -0,0 +1,64 @@
+package intrusive_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedHeap(t *testing.T) {
+	th := new(intrusive.TypedHeap[recordOfHeap]).Init(func(r *recordOfHeap) *intrusive.HeapNode {
+		return &r.HeapNode
+	}, func(r1 *recordOfHeap, r2 *recordOfHeap) bool {
+		return r1.Value < r2.Value
+	}, 0)
+	_, ok := th.GetTop()
+	assert.False(t, ok)
+	r, ok := th.ReplaceTop(&recordOfHeap{Value: 0})
+	assert.False(t, ok)
+	assert.Nil(t, r)
+	rs := make([]recordOfHeap, 10)
+	for _, i := range rand.Perm(len(rs)) {
+		rs[i].Value = i + 1
+		th.Insert(&rs[i])
+	}
+	assert.Equal(t, len(rs)+1, th.NumberOfElements())
+	r, ok = th.PopTop()
+	if assert.True(t, ok) {
+		assert.Equal(t, 0, r.Value)
+		assert.True(t, r.HeapNode.IsReset())
+	}
+	assert.Equal(t, &rs[0], th.PushPop(&recordOfHeap{Value: 100}))
+	r, ok = th.ReplaceTop(&rs[0])
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[1], r)
+	}
+	rs[0].Value = 50
+	th.Fix(&rs[0])
+	th.Remove(&rs[5])
+	assert.NoError(t, th.Heap().Validate())
+	n := 0
+	for r := range th.All() {
+		if r.Value%2 == 0 {
+			th.Remove(r)
+		}
+		n++
+	}
+	assert.Equal(t, 9, n)
+	var vs []int
+	for {
+		r, ok := th.PopTop()
+		if !ok {
+			break
+		}
+		vs = append(vs, r.Value)
+	}
+	assert.Equal(t, []int{3, 5, 7, 9}, vs)
+	th.Insert(&rs[1])
+	th.Clear(func(r *recordOfHeap) {
+		assert.Equal(t, &rs[1], r)
+	})
+	assert.True(t, th.IsEmpty())
+}
@@ -0,0 +1,141 @@
+package intrusive
+
+import "iter"
+
+// TypedList presents a doubly-linked list of elements of type T, each of
+// which contains a ListNode field, so that elements are used directly
+// instead of nodes.
+type TypedList[T any] struct {
+	l          List
+	nodeOffset uintptr
+}
+
+// Init initializes the list with the given accessor, which returns the
+// ListNode field of an element, and then returns the list.
+func (tl *TypedList[T]) Init(nodeAccessor func(*T) *ListNode) *TypedList[T] {
+	tl.l.Init()
+	tl.nodeOffset = offsetOfNode(nodeAccessor)
+	return tl
+}
+
+// Append inserts the given element at the end of the list.
+func (tl *TypedList[T]) Append(x *T) {
+	tl.l.AppendNode(tl.nodeOf(x))
+}
+
+// Prepend inserts the given element at the beginning of the list.
+func (tl *TypedList[T]) Prepend(x *T) {
+	tl.l.PrependNode(tl.nodeOf(x))
+}
+
+// InsertBefore inserts the given element before the given other element
+// in the list.
+func (tl *TypedList[T]) InsertBefore(x *T, other *T) {
+	tl.l.InsertNodeBefore(tl.nodeOf(x), tl.nodeOf(other))
+}
+
+// InsertAfter inserts the given element after the given other element
+// in the list.
+func (tl *TypedList[T]) InsertAfter(x *T, other *T) {
+	tl.l.InsertNodeAfter(tl.nodeOf(x), tl.nodeOf(other))
+}
+
+// Remove removes the given element from the list and then resets the
+// ListNode field of the element.
+func (tl *TypedList[T]) Remove(x *T) {
+	tl.l.RemoveNode(tl.nodeOf(x))
+}
+
+// Clear removes all elements from the list in O(n) time, resets the
+// ListNode fields of the elements and calls the given callback, if any,
+// with every element.
+func (tl *TypedList[T]) Clear(onElement func(*T)) {
+	if onElement == nil {
+		tl.l.Clear(nil)
+		return
+	}
+
+	tl.l.Clear(func(node *ListNode) {
+		onElement(tl.elementOf(node))
+	})
+}
+
+// Head returns the first element of the list.
+// If the list is empty, it returns false.
+func (tl *TypedList[T]) Head() (*T, bool) {
+	return tl.checkNode(tl.l.Head())
+}
+
+// Tail returns the last element of the list.
+// If the list is empty, it returns false.
+func (tl *TypedList[T]) Tail() (*T, bool) {
+	return tl.checkNode(tl.l.Tail())
+}
+
+// Next returns the next element to the given element in the list.
+// If the given element is at the end of the list, it returns false.
+func (tl *TypedList[T]) Next(x *T) (*T, bool) {
+	return tl.checkNode(tl.nodeOf(x).Next())
+}
+
+// Prev returns the previous element to the given element in the list.
+// If the given element is at the beginning of the list, it returns false.
+func (tl *TypedList[T]) Prev(x *T) (*T, bool) {
+	return tl.checkNode(tl.nodeOf(x).Prev())
+}
+
+// All returns an iterator, for use with range-over-func, over all
+// elements in the list.
+// It's safe to remove the yielded element during the iteration.
+func (tl *TypedList[T]) All() iter.Seq[*T] {
+	return tl.elements(tl.l.All())
+}
+
+// Backward returns an iterator, for use with range-over-func, over all
+// elements in the list in reverse order.
+// It's safe to remove the yielded element during the iteration.
+func (tl *TypedList[T]) Backward() iter.Seq[*T] {
+	return tl.elements(tl.l.Backward())
+}
+
+// IsEmpty indicates whether the list is empty.
+func (tl *TypedList[T]) IsEmpty() bool {
+	return tl.l.IsEmpty()
+}
+
+// NumberOfElements returns the number of elements in the list.
+func (tl *TypedList[T]) NumberOfElements() int {
+	return tl.l.NumberOfNodes()
+}
+
+// List returns the underlying list of the ListNode fields of elements,
+// for the operations not covered by TypedList.
+func (tl *TypedList[T]) List() *List {
+	return &tl.l
+}
+
+func (tl *TypedList[T]) elements(nodes iter.Seq[*ListNode]) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		for node := range nodes {
+			if !yield(tl.elementOf(node)) {
+				return
+			}
+		}
+	}
+}
+
+func (tl *TypedList[T]) checkNode(node *ListNode) (*T, bool) {
+	if node.IsNull(&tl.l) {
+		return nil, false
+	}
+
+	return tl.elementOf(node), true
+}
+
+func (tl *TypedList[T]) elementOf(node *ListNode) *T {
+	return elementOfNode[T](node, tl.nodeOffset)
+}
+
+func (tl *TypedList[T]) nodeOf(x *T) *ListNode {
+	return nodeOfElement[ListNode](x, tl.nodeOffset)
+}
@@ -0,0 +1,84 @@
+package intrusive_test
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedList(t *testing.T) {
+	tl := new(intrusive.TypedList[recordOfList]).Init(func(r *recordOfList) *intrusive.ListNode {
+		return &r.ListNode
+	})
+	_, ok := tl.Head()
+	assert.False(t, ok)
+	rs := make([]recordOfList, 6)
+	for i := range rs {
+		rs[i].Value = i + 1
+	}
+	tl.Append(&rs[1])
+	tl.Prepend(&rs[0])
+	tl.Append(&rs[4])
+	tl.InsertBefore(&rs[2], &rs[4])
+	tl.InsertAfter(&rs[3], &rs[2])
+	tl.Append(&rs[5])
+	assert.Equal(t, 6, tl.NumberOfElements())
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, valuesOfTypedList(tl.All()))
+	assert.Equal(t, []int{6, 5, 4, 3, 2, 1}, valuesOfTypedList(tl.Backward()))
+	r, ok := tl.Head()
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[0], r)
+	}
+	r, ok = tl.Tail()
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[5], r)
+	}
+	r, ok = tl.Next(&rs[2])
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[3], r)
+	}
+	r, ok = tl.Prev(&rs[2])
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[1], r)
+	}
+	_, ok = tl.Next(&rs[5])
+	assert.False(t, ok)
+	_, ok = tl.Prev(&rs[0])
+	assert.False(t, ok)
+	for r := range tl.All() {
+		if r.Value%2 == 0 {
+			tl.Remove(r)
+			assert.True(t, r.ListNode.IsReset())
+		}
+	}
+	assert.Equal(t, []int{1, 3, 5}, valuesOfTypedList(tl.All()))
+	assert.NoError(t, tl.List().Validate())
+	var vs []int
+	tl.Clear(func(r *recordOfList) {
+		vs = append(vs, r.Value)
+	})
+	assert.Equal(t, []int{1, 3, 5}, vs)
+	assert.True(t, tl.IsEmpty())
+	assert.Equal(t, 0, tl.NumberOfElements())
+}
+
+func TestTypedListInitPanic(t *testing.T) {
+	var ln intrusive.ListNode
+	assert.PanicsWithValue(t, "intrusive: node accessor not returning a field of the element", func() {
+		new(intrusive.TypedList[recordOfList]).Init(func(*recordOfList) *intrusive.ListNode {
+			return &ln
+		})
+	})
+}
+
+func valuesOfTypedList(records iter.Seq[*recordOfList]) []int {
+	var vs []int
+
+	for r := range records {
+		vs = append(vs, r.Value)
+	}
+
+	return vs
+}
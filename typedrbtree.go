@@ -0,0 +1,213 @@
+package intrusive
+
+import "iter"
+
+// TypedRBTree presents a red-black tree of elements of type T, each of
+// which contains an RBTreeNode field, so that elements are used directly
+// instead of nodes, as well as by the orderer and the comparer.
+type TypedRBTree[T any] struct {
+	rbt        RBTree
+	nodeOffset uintptr
+}
+
+// Init initializes the tree with the given accessor, which returns the
+// RBTreeNode field of an element, the given orderer, which indicates
+// whether the given element 1 is not greater than the given element 2, and
+// the given comparer, which compares the given element with the given key
+// as RBTreeNodeComparer does, and then returns the tree.
+func (trbt *TypedRBTree[T]) Init(nodeAccessor func(*T) *RBTreeNode, orderer func(x1 *T, x2 *T) bool, comparer func(x *T, key interface{}) int64) *TypedRBTree[T] {
+	trbt.nodeOffset = offsetOfNode(nodeAccessor)
+
+	trbt.rbt.Init(func(rbtn1 *RBTreeNode, rbtn2 *RBTreeNode) bool {
+		return orderer(trbt.elementOf(rbtn1), trbt.elementOf(rbtn2))
+	}, func(rbtn *RBTreeNode, key interface{}) int64 {
+		return comparer(trbt.elementOf(rbtn), key)
+	})
+
+	return trbt
+}
+
+// Insert inserts the given element to the tree.
+func (trbt *TypedRBTree[T]) Insert(x *T) {
+	trbt.rbt.InsertNode(trbt.nodeOf(x))
+}
+
+// InsertUnique inserts the given element with the given key to the tree
+// unless an element with an identical key exists, and then returns the
+// element with the given key in the tree and a boolean indicating whether
+// the given element has been inserted.
+// The given key must be the key of the given element.
+func (trbt *TypedRBTree[T]) InsertUnique(x *T, key interface{}) (*T, bool) {
+	rbtn, ok := trbt.rbt.InsertNodeUnique(trbt.nodeOf(x), key)
+	return trbt.elementOf(rbtn), ok
+}
+
+// FindOrInsert finds an element with the given key in the tree, or
+// otherwise inserts the element created by the given factory with the
+// given key to the tree, and then returns the element and a boolean
+// indicating whether the element has been inserted.
+func (trbt *TypedRBTree[T]) FindOrInsert(key interface{}, elementFactory func() *T) (*T, bool) {
+	rbtn, ok := trbt.rbt.FindOrInsertNode(key, func() *RBTreeNode {
+		return trbt.nodeOf(elementFactory())
+	})
+
+	return trbt.elementOf(rbtn), ok
+}
+
+// Remove removes the given element from the tree and then resets the
+// RBTreeNode field of the element.
+func (trbt *TypedRBTree[T]) Remove(x *T) {
+	trbt.rbt.RemoveNode(trbt.nodeOf(x))
+}
+
+// Update repositions the given element in the tree after the key of
+// the element has changed, as *RBTree.UpdateNode does, and then returns
+// a boolean indicating whether the element has been moved.
+func (trbt *TypedRBTree[T]) Update(x *T) bool {
+	return trbt.rbt.UpdateNode(trbt.nodeOf(x))
+}
+
+// Replace puts the given new element in the exact position of the given
+// old element in the tree, as *RBTree.ReplaceNode does, and then resets
+// the RBTreeNode field of the old element.
+// The key of the new element must fit in the position of the old element.
+func (trbt *TypedRBTree[T]) Replace(oldElement *T, newElement *T) {
+	trbt.rbt.ReplaceNode(trbt.nodeOf(oldElement), trbt.nodeOf(newElement))
+}
+
+// Clear removes all elements from the tree in O(n) time, resets the
+// RBTreeNode fields of the elements and calls the given callback, if any,
+// with every element in post-order.
+func (trbt *TypedRBTree[T]) Clear(onElement func(*T)) {
+	if onElement == nil {
+		trbt.rbt.Clear(nil)
+		return
+	}
+
+	trbt.rbt.Clear(func(rbtn *RBTreeNode) {
+		onElement(trbt.elementOf(rbtn))
+	})
+}
+
+// Find finds an element with the given key in the tree and then returns
+// the element.
+// If no such element exists, it returns false.
+func (trbt *TypedRBTree[T]) Find(key interface{}) (*T, bool) {
+	return trbt.checkNode(trbt.rbt.FindNode(key))
+}
+
+// FindLowerBound finds the first element with a key not less than the
+// given key in the tree and then returns the element.
+// If no such element exists, it returns false.
+func (trbt *TypedRBTree[T]) FindLowerBound(key interface{}) (*T, bool) {
+	return trbt.checkNode(trbt.rbt.FindLowerBound(key))
+}
+
+// FindUpperBound finds the first element with a key greater than the
+// given key in the tree and then returns the element.
+// If no such element exists, it returns false.
+func (trbt *TypedRBTree[T]) FindUpperBound(key interface{}) (*T, bool) {
+	return trbt.checkNode(trbt.rbt.FindUpperBound(key))
+}
+
+// FindFloor finds the last element with a key not greater than the given
+// key in the tree and then returns the element.
+// If no such element exists, it returns false.
+func (trbt *TypedRBTree[T]) FindFloor(key interface{}) (*T, bool) {
+	return trbt.checkNode(trbt.rbt.FindFloor(key))
+}
+
+// FindCeiling finds the first element with a key not less than the given
+// key in the tree and then returns the element.
+// If no such element exists, it returns false.
+func (trbt *TypedRBTree[T]) FindCeiling(key interface{}) (*T, bool) {
+	return trbt.checkNode(trbt.rbt.FindCeiling(key))
+}
+
+// GetMin returns the element with the minimum key in the tree.
+// If the tree is empty, it returns false.
+func (trbt *TypedRBTree[T]) GetMin() (*T, bool) {
+	return trbt.checkNode(trbt.rbt.GetMin())
+}
+
+// GetMax returns the element with the maximum key in the tree.
+// If the tree is empty, it returns false.
+func (trbt *TypedRBTree[T]) GetMax() (*T, bool) {
+	return trbt.checkNode(trbt.rbt.GetMax())
+}
+
+// GetNext returns the next element to the given element in the tree.
+// If the given element is the last one, it returns false.
+func (trbt *TypedRBTree[T]) GetNext(x *T) (*T, bool) {
+	return trbt.checkNode(trbt.nodeOf(x).GetNext(&trbt.rbt))
+}
+
+// GetPrev returns the previous element to the given element in the tree.
+// If the given element is the first one, it returns false.
+func (trbt *TypedRBTree[T]) GetPrev(x *T) (*T, bool) {
+	return trbt.checkNode(trbt.nodeOf(x).GetPrev(&trbt.rbt))
+}
+
+// All returns an iterator, for use with range-over-func, over all
+// elements in the tree in order.
+// It's safe to remove the yielded element during the iteration.
+func (trbt *TypedRBTree[T]) All() iter.Seq[*T] {
+	return trbt.elements(trbt.rbt.All())
+}
+
+// Backward returns an iterator, for use with range-over-func, over all
+// elements in the tree in reverse order.
+// It's safe to remove the yielded element during the iteration.
+func (trbt *TypedRBTree[T]) Backward() iter.Seq[*T] {
+	return trbt.elements(trbt.rbt.Backward())
+}
+
+// Range returns an iterator, for use with range-over-func, over elements
+// with keys within the half-open range [minKey, maxKey) in the tree in
+// order.
+// It's safe to remove the yielded element during the iteration.
+func (trbt *TypedRBTree[T]) Range(minKey interface{}, maxKey interface{}) iter.Seq[*T] {
+	return trbt.elements(trbt.rbt.Range(minKey, maxKey))
+}
+
+// IsEmpty indicates whether the tree is empty.
+func (trbt *TypedRBTree[T]) IsEmpty() bool {
+	return trbt.rbt.IsEmpty()
+}
+
+// NumberOfElements returns the number of elements in the tree.
+func (trbt *TypedRBTree[T]) NumberOfElements() int {
+	return trbt.rbt.NumberOfNodes()
+}
+
+// RBTree returns the underlying tree of the RBTreeNode fields of elements,
+// for the operations not covered by TypedRBTree.
+func (trbt *TypedRBTree[T]) RBTree() *RBTree {
+	return &trbt.rbt
+}
+
+func (trbt *TypedRBTree[T]) elements(nodes iter.Seq[*RBTreeNode]) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		for rbtn := range nodes {
+			if !yield(trbt.elementOf(rbtn)) {
+				return
+			}
+		}
+	}
+}
+
+func (trbt *TypedRBTree[T]) checkNode(rbtn *RBTreeNode, ok bool) (*T, bool) {
+	if !ok {
+		return nil, false
+	}
+
+	return trbt.elementOf(rbtn), true
+}
+
+func (trbt *TypedRBTree[T]) elementOf(rbtn *RBTreeNode) *T {
+	return elementOfNode[T](rbtn, trbt.nodeOffset)
+}
+
+func (trbt *TypedRBTree[T]) nodeOf(x *T) *RBTreeNode {
+	return nodeOfElement[RBTreeNode](x, trbt.nodeOffset)
+}
@@ -0,0 +1,101 @@
+package intrusive_test
+
+import (
+	"iter"
+	"math/rand"
+	"testing"
+
+	"github.com/roy2220/intrusive"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedRBTree(t *testing.T) {
+	trbt := new(intrusive.TypedRBTree[recordOfRBTree]).Init(func(r *recordOfRBTree) *intrusive.RBTreeNode {
+		return &r.RBTreeNode
+	}, func(r1 *recordOfRBTree, r2 *recordOfRBTree) bool {
+		return r1.Value < r2.Value
+	}, func(r *recordOfRBTree, value interface{}) int64 {
+		return int64(r.Value - value.(int))
+	})
+	_, ok := trbt.GetMin()
+	assert.False(t, ok)
+	rs := make([]recordOfRBTree, 10)
+	for _, i := range rand.Perm(len(rs)) {
+		rs[i].Value = 2 * i
+		trbt.Insert(&rs[i])
+	}
+	assert.Equal(t, len(rs), trbt.NumberOfElements())
+	for i, tt := range []struct {
+		Find  func(interface{}) (*recordOfRBTree, bool)
+		Key   int
+		Index int
+	}{
+		{Find: trbt.Find, Key: 6, Index: 3},
+		{Find: trbt.Find, Key: 7, Index: -1},
+		{Find: trbt.FindLowerBound, Key: 7, Index: 4},
+		{Find: trbt.FindUpperBound, Key: 8, Index: 5},
+		{Find: trbt.FindUpperBound, Key: 18, Index: -1},
+		{Find: trbt.FindFloor, Key: 7, Index: 3},
+		{Find: trbt.FindFloor, Key: -1, Index: -1},
+		{Find: trbt.FindCeiling, Key: 7, Index: 4},
+	} {
+		r, ok := tt.Find(tt.Key)
+		if tt.Index < 0 {
+			assert.False(t, ok, "case %d", i)
+		} else if assert.True(t, ok, "case %d", i) {
+			assert.Equal(t, &rs[tt.Index], r, "case %d", i)
+		}
+	}
+	r, ok := trbt.InsertUnique(&recordOfRBTree{Value: 4}, 4)
+	assert.False(t, ok)
+	assert.Equal(t, &rs[2], r)
+	r, ok = trbt.FindOrInsert(5, func() *recordOfRBTree { return &recordOfRBTree{Value: 5} })
+	assert.True(t, ok)
+	assert.Equal(t, 5, r.Value)
+	r2, ok := trbt.GetNext(&rs[2])
+	if assert.True(t, ok) {
+		assert.Equal(t, r, r2)
+	}
+	r2, ok = trbt.GetPrev(r)
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[2], r2)
+	}
+	trbt.Remove(r)
+	assert.True(t, r.RBTreeNode.IsReset())
+	rs[0].Value = 100
+	assert.True(t, trbt.Update(&rs[0]))
+	r = &recordOfRBTree{Value: 100}
+	trbt.Replace(&rs[0], r)
+	assert.True(t, rs[0].RBTreeNode.IsReset())
+	r2, ok = trbt.GetMax()
+	if assert.True(t, ok) {
+		assert.Equal(t, r, r2)
+	}
+	r2, ok = trbt.GetMin()
+	if assert.True(t, ok) {
+		assert.Equal(t, &rs[1], r2)
+	}
+	assert.Equal(t, []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 100}, valuesOfTypedRBTree(trbt.All()))
+	assert.Equal(t, []int{100, 18, 16, 14, 12, 10, 8, 6, 4, 2}, valuesOfTypedRBTree(trbt.Backward()))
+	assert.Equal(t, []int{6, 8, 10}, valuesOfTypedRBTree(trbt.Range(5, 12)))
+	for r := range trbt.Range(5, 12) {
+		trbt.Remove(r)
+	}
+	assert.NoError(t, trbt.RBTree().Validate())
+	var vs []int
+	trbt.Clear(func(r *recordOfRBTree) {
+		vs = append(vs, r.Value)
+	})
+	assert.Len(t, vs, 7)
+	assert.True(t, trbt.IsEmpty())
+}
+
+func valuesOfTypedRBTree(records iter.Seq[*recordOfRBTree]) []int {
+	var vs []int
+
+	for r := range records {
+		vs = append(vs, r.Value)
+	}
+
+	return vs
+}